@@ -1,90 +1,224 @@
 package betfair
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"strings"
 )
 
-type developerApp struct {
+// Developer App Version, DelayData versions get delayed prices and are free
+// of charge
+type DeveloperAppVersion struct {
+	Owner                string
+	VersionId            int64
+	Version              string
+	ApplicationKey       string
+	DelayData            bool
+	SubscriptionRequired bool
+	OwnerManaged         bool
+	Active               bool
+	VendorId             string
+	VendorSecret         string
+}
+
+// Developer App
+type DeveloperApp struct {
 	AppName     string
-	AppId       uint32
-	AppVersions []struct {
-		Owner                string
-		VersionId            uint32
-		Version              string
-		ApplicationKey       string
-		DelayData            bool
-		SubscriptionRequired bool
-		OwnerManaged         bool
-		Active               bool
-	}
+	AppId       int64
+	AppVersions []DeveloperAppVersion
 }
 
-// Sets request application key by provided name and delay info
+// Sets request application key by provided name and delay info. Apps are
+// fetched on first use and cached, GetDeveloperAppKeys refreshes them. More
+// than one active version matching is an error.
 func (s *Session) SetUsedApplication(name string, delay bool) error {
+	return s.SetUsedApplicationContext(context.Background(), name, delay)
+}
+
+// Like SetUsedApplication, ctx controls the developer app keys request
+func (s *Session) SetUsedApplicationContext(ctx context.Context, name string,
+	delay bool) error {
 	// first set session developer apps
-	if s.developerApps == nil {
-		var apps []developerApp
-		resp, err := s.GetDeveloperAppKeys()
-		if err != nil {
+	s.mu.RLock()
+	apps := s.developerApps
+	s.mu.RUnlock()
+	if apps == nil {
+		var err error
+		if apps, err = s.GetDeveloperAppKeysContext(ctx); err != nil {
 			return err
 		}
-		if err := json.Unmarshal([]byte(resp), &apps); err != nil {
-			return err
-		}
-		s.developerApps = &apps
 	}
 
 	// find application by provided app name and delay info
-	for _, app := range *s.developerApps {
+	var keys []string
+	for _, app := range apps {
 		if app.AppName != name {
 			continue
 		}
 
-		for _, versions := range app.AppVersions {
-			if versions.DelayData != delay || !versions.Active {
+		for _, version := range app.AppVersions {
+			if version.DelayData != delay || !version.Active {
 				continue
 			}
-
-			s.requestCredentials.ApplicationKey = versions.ApplicationKey
+			keys = append(keys, version.ApplicationKey)
 		}
 	}
 
-	if s.requestCredentials.ApplicationKey == "" {
-		return errors.New(fmt.Sprintf("%s application not found", name))
+	switch len(keys) {
+	case 0:
+		return fmt.Errorf("%s application not found", name)
+	case 1:
+		s.setApplicationKey(keys[0])
+		return nil
 	}
+	return fmt.Errorf("%s application has %d active versions with delay %t",
+		name, len(keys), delay)
+}
 
-	return nil
+// Returns developer apps of the account and refreshes the apps cached for
+// SetUsedApplication
+func (s *Session) GetDeveloperAppKeys() ([]DeveloperApp, error) {
+	return s.GetDeveloperAppKeysContext(context.Background())
 }
 
-func (s *Session) GetDeveloperAppKeys() (string, error) {
-	payload := strings.NewReader("")
-	resp, err := doRequest(s, "account", "getDeveloperAppKeys", payload)
+// Like GetDeveloperAppKeys, ctx controls the request
+func (s *Session) GetDeveloperAppKeysContext(ctx context.Context) (
+	[]DeveloperApp, error) {
+	var apps []DeveloperApp
+	err := accountRequest(ctx, "getDeveloperAppKeys", s, struct{}{}, &apps)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if apps == nil {
+		apps = []DeveloperApp{}
 	}
 
-	return string(resp), nil
+	s.mu.Lock()
+	s.developerApps = apps
+	s.mu.Unlock()
+	return apps, nil
 }
 
-func (s *Session) GetAccountFunds() (string, error) {
-	payload := strings.NewReader("")
-	resp, err := doRequest(s, "account", "getAccountFunds", payload)
-	if err != nil {
-		return "", err
+// Creates the live and delayed application keys of a new app named
+// appName, cached apps are fetched again on next SetUsedApplication
+func (s *Session) CreateDeveloperAppKeys(appName string) (*DeveloperApp,
+	error) {
+	return s.CreateDeveloperAppKeysContext(context.Background(), appName)
+}
+
+// Like CreateDeveloperAppKeys, ctx controls the request
+func (s *Session) CreateDeveloperAppKeysContext(ctx context.Context,
+	appName string) (*DeveloperApp, error) {
+	if appName == "" {
+		return nil, errRequired("createDeveloperAppKeys", "app name")
+	}
+
+	params := struct {
+		AppName string `json:"appName"`
+	}{appName}
+	var app DeveloperApp
+	if err := accountRequest(ctx, "createDeveloperAppKeys", s, params,
+		&app); err != nil {
+		return nil, err
 	}
 
-	return string(resp), nil
+	s.mu.Lock()
+	s.developerApps = nil
+	s.mu.Unlock()
+	return &app, nil
+}
+
+// Wallet holding account funds
+type Wallet string
+
+const (
+	WalletUK Wallet = "UK"
+)
+
+// Account balances of a wallet
+type AccountFundsResponse struct {
+	AvailableToBetBalance float64
+	Exposure              float64
+	RetainedCommission    float64
+	ExposureLimit         float64
+	DiscountRate          float64
+	PointsBalance         int
+	Wallet                Wallet
 }
 
-func (s *Session) GetAccountDetails() (string, error) {
-	payload := strings.NewReader("")
-	resp, err := doRequest(s, "account", "getAccountDetails", payload)
+// Account holder details
+type AccountDetailsResponse struct {
+	CurrencyCode  string
+	FirstName     string
+	LastName      string
+	LocaleCode    string
+	Region        string
+	Timezone      string
+	DiscountRate  float64
+	PointsBalance int
+	CountryCode   string
+}
+
+// Returns funds of the wallet, the UK wallet unless one is given
+func (s *Session) GetAccountFunds(wallet ...Wallet) (*AccountFundsResponse,
+	error) {
+	return s.GetAccountFundsContext(context.Background(), wallet...)
+}
+
+// Like GetAccountFunds, ctx controls the request
+func (s *Session) GetAccountFundsContext(ctx context.Context,
+	wallet ...Wallet) (*AccountFundsResponse, error) {
+	var params struct {
+		Wallet Wallet `json:"wallet,omitempty"`
+	}
+	if len(wallet) > 0 {
+		params.Wallet = wallet[0]
+	}
+
+	var funds AccountFundsResponse
+	if err := accountRequest(ctx, "getAccountFunds", s, params,
+		&funds); err != nil {
+		return nil, err
+	}
+	return &funds, nil
+}
+
+// Returns details of the account
+func (s *Session) GetAccountDetails() (*AccountDetailsResponse, error) {
+	return s.GetAccountDetailsContext(context.Background())
+}
+
+// Like GetAccountDetails, ctx controls the request
+func (s *Session) GetAccountDetailsContext(ctx context.Context) (
+	*AccountDetailsResponse, error) {
+	var details AccountDetailsResponse
+	if err := accountRequest(ctx, "getAccountDetails", s, struct{}{},
+		&details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// sends params to the account method and decodes the response into r
+func accountRequest(ctx context.Context, method string, s *Session,
+	params interface{}, r interface{}) error {
+	p, err := json.Marshal(params)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("%s: encoding request: %w", method, err)
 	}
+	s.log().Debug("account request", "method", method, "payload", string(p))
 
-	return string(resp), nil
+	payload := strings.NewReader(string(p))
+	resp, err := doRequest(ctx, s, "account", method, payload)
+	if err != nil {
+		s.log().Warn("account request failed", "method", method, "err", err)
+		return err
+	}
+	s.log().Debug("account response", "method", method, "body", string(resp))
+
+	if err := json.Unmarshal(resp, r); err != nil {
+		return fmt.Errorf("%s: decoding response: %w", method, err)
+	}
+	return nil
 }
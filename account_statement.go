@@ -0,0 +1,151 @@
+package betfair
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Items included in an account statement
+const (
+	IncludeItemAll                 = "ALL"
+	IncludeItemDepositsWithdrawals = "DEPOSITS_WITHDRAWALS"
+	IncludeItemExchange            = "EXCHANGE"
+	IncludeItemPokerRoom           = "POKER_ROOM"
+)
+
+// Statement item classes, items of unknown class carry their values in
+// ItemClassData under the unknownStatementItem key
+const (
+	ItemClassUnknown = "UNKNOWN"
+)
+
+// Outcomes of bets in legacy statement data
+const (
+	WinLoseResultErr           = "RESULT_ERR"
+	WinLoseResultFix           = "RESULT_FIX"
+	WinLoseResultLost          = "RESULT_LOST"
+	WinLoseResultNotApplicable = "RESULT_NOT_APPLICABLE"
+	WinLoseResultWon           = "RESULT_WON"
+	WinLoseCommissionReversal  = "COMMISSION_REVERSAL"
+)
+
+// maximum records of a single account statement call
+const maxStatementRecordCount = 100
+
+// Parameters of GetAccountStatement. Items of the last 90 days are returned
+// unless ItemDateRange is set, IncludeItem defaults to ALL.
+type AccountStatementRequest struct {
+	Locale        string     `json:"locale,omitempty"`
+	FromRecord    int        `json:"fromRecord,omitempty"`
+	RecordCount   int        `json:"recordCount,omitempty"`
+	ItemDateRange *TimeRange `json:"itemDateRange,omitempty"`
+	IncludeItem   string     `json:"includeItem,omitempty"`
+	Wallet        Wallet     `json:"wallet,omitempty"`
+}
+
+func (r *AccountStatementRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("getAccountStatement")
+	}
+	if r.RecordCount < 0 || r.RecordCount > maxStatementRecordCount {
+		return fmt.Errorf("getAccountStatement: record count must be between "+
+			"0 and %d", maxStatementRecordCount)
+	}
+	return nil
+}
+
+// Statement Legacy Data, item details as in the legacy account statement
+type StatementLegacyData struct {
+	AvgPrice        float64
+	BetSize         float64
+	BetType         string
+	BetCategoryType string
+	CommissionRate  string
+	EventId         int64
+	EventTypeId     int64
+	FullMarketName  string
+	GrossBetAmount  float64
+	MarketName      string
+	MarketType      string
+	PlacedDate      time.Time
+	SelectionId     uint32
+	SelectionName   string
+	StartDate       time.Time
+	TransactionType string
+	TransactionId   int64
+	WinLose         string
+}
+
+// Statement Item
+type StatementItem struct {
+	RefId         string
+	ItemDate      time.Time
+	Amount        float64
+	Balance       float64
+	ItemClass     string
+	ItemClassData map[string]string
+	LegacyData    StatementLegacyData
+}
+
+// Account Statement Report
+type AccountStatementReport struct {
+	AccountStatement []StatementItem
+	MoreAvailable    bool
+}
+
+// Returns a page of account statement items, see GetAllAccountStatement for
+// every page
+func (s *Session) GetAccountStatement(r *AccountStatementRequest) (
+	*AccountStatementReport, error) {
+	return s.GetAccountStatementContext(context.Background(), r)
+}
+
+// Like GetAccountStatement, ctx controls the request
+func (s *Session) GetAccountStatementContext(ctx context.Context,
+	r *AccountStatementRequest) (*AccountStatementReport, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	var report AccountStatementReport
+	if err := accountRequest(ctx, "getAccountStatement", s, r,
+		&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Returns account statement items of every page starting from
+// r.FromRecord, pages are requested while more are available
+func (s *Session) GetAllAccountStatement(r *AccountStatementRequest) (
+	[]StatementItem, error) {
+	return s.GetAllAccountStatementContext(context.Background(), r)
+}
+
+// Like GetAllAccountStatement, ctx controls every page request and stops
+// paging once done
+func (s *Session) GetAllAccountStatementContext(ctx context.Context,
+	r *AccountStatementRequest) ([]StatementItem, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	var items []StatementItem
+	page := *r
+	for {
+		report, err := s.GetAccountStatementContext(ctx, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, report.AccountStatement...)
+
+		if !report.MoreAvailable || len(report.AccountStatement) == 0 {
+			return items, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page.FromRecord += len(report.AccountStatement)
+	}
+}
@@ -0,0 +1,290 @@
+package betfair
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_GetAccountFunds(t *testing.T) {
+	s := newMethodTestServer(t, "/account/", testMethods{
+		"getAccountFunds": func(p map[string]interface{}) string {
+			if p["wallet"] != "UK" {
+				t.Error("wallet not sent", p)
+			}
+			return `{"availableToBetBalance":12.5,"exposure":-3.2,` +
+				`"retainedCommission":0,"exposureLimit":-10000,` +
+				`"discountRate":0,"pointsBalance":10,"wallet":"UK"}`
+		},
+		"getAccountDetails": func(p map[string]interface{}) string {
+			return `{"currencyCode":"EUR","firstName":"A","lastName":"B",` +
+				`"localeCode":"en","region":"GBR","timezone":"GMT",` +
+				`"discountRate":0,"pointsBalance":10,"countryCode":"IE"}`
+		},
+	})
+
+	funds, err := s.GetAccountFunds(WalletUK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if funds.AvailableToBetBalance != 12.5 || funds.Exposure != -3.2 ||
+		funds.ExposureLimit != -10000 || funds.Wallet != WalletUK {
+		t.Error("account funds not decoded", funds)
+	}
+
+	details, err := s.GetAccountDetails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.CurrencyCode != "EUR" || details.CountryCode != "IE" {
+		t.Error("account details not decoded", details)
+	}
+}
+
+func Test_GetAllAccountStatement(t *testing.T) {
+	var pages int
+	s := newMethodTestServer(t, "/account/", testMethods{
+		"getAccountStatement": func(p map[string]interface{}) string {
+			pages++
+			if p["includeItem"] != IncludeItemExchange {
+				t.Error("include item not sent", p)
+			}
+			if p["fromRecord"] == nil {
+				return `{"accountStatement":[{"refId":"1","amount":-2,` +
+					`"balance":98,"itemClass":"UNKNOWN","itemClassData":` +
+					`{"unknownStatementItem":"{}"},"legacyData":{"avgPrice":2.5,` +
+					`"betSize":2,"marketName":"Match Odds",` +
+					`"winLose":"RESULT_LOST"}}],"moreAvailable":true}`
+			}
+			if p["fromRecord"] != 1.0 {
+				t.Error("wrong page requested", p)
+			}
+			return `{"accountStatement":[{"refId":"2","amount":5,` +
+				`"balance":103,"itemClass":"UNKNOWN"}],"moreAvailable":false}`
+		},
+	})
+
+	items, err := s.GetAllAccountStatement(&AccountStatementRequest{
+		IncludeItem: IncludeItemExchange,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 2 || len(items) != 2 || items[1].Balance != 103 {
+		t.Fatal("pages not joined", pages, items)
+	}
+	if items[0].LegacyData.WinLose != WinLoseResultLost ||
+		items[0].LegacyData.AvgPrice != 2.5 ||
+		items[0].ItemClassData["unknownStatementItem"] != "{}" {
+		t.Error("statement item not decoded", items[0])
+	}
+
+	if _, err := s.GetAccountStatement(&AccountStatementRequest{
+		RecordCount: 101,
+	}); err == nil {
+		t.Error("record count over limit accepted")
+	}
+}
+
+func Test_CurrencyConverter(t *testing.T) {
+	var calls int
+	s := newMethodTestServer(t, "/account/", testMethods{
+		"listCurrencyRates": func(p map[string]interface{}) string {
+			calls++
+			if p["fromCurrency"] != "GBP" {
+				t.Error("from currency not sent", p)
+			}
+			return `[{"currencyCode":"EUR","rate":1.25},` +
+				`{"currencyCode":"AUD","rate":2},` +
+				`{"currencyCode":"USD","rate":1.2731}]`
+		},
+	})
+
+	now := time.Unix(0, 0)
+	c := NewCurrencyConverter(s, time.Minute)
+	c.now = func() time.Time { return now }
+
+	if v, err := c.FromGBP(2, "EUR"); err != nil || v != 2.5 {
+		t.Error("GBP not converted", v, err)
+	}
+	if v, err := c.Convert(5, "eur", "AUD"); err != nil || v != 8 {
+		t.Error("EUR not converted to AUD", v, err)
+	}
+	if v, err := c.ToGBP(4, "AUD"); err != nil || v != 2 {
+		t.Error("AUD not converted to GBP", v, err)
+	}
+	for currency, want := range map[string]float64{"GBP": 1, "EUR": 1.25,
+		"AUD": 2, "USD": 1.28} {
+		if v, err := c.MinimumStake(currency); err != nil || v != want {
+			t.Error("unexpected minimum stake", currency, v, err)
+		}
+	}
+	if calls != 1 {
+		t.Error("rates not cached", calls)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.Rate("EUR"); err != nil || calls != 2 {
+		t.Error("expired rates not fetched", calls, err)
+	}
+
+	if _, err := c.Rate("XYZ"); err == nil {
+		t.Error("unknown currency converted")
+	}
+}
+
+func Test_CurrencyConverterSlowFetch(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	s := newMethodTestServer(t, "/account/", testMethods{
+		"listCurrencyRates": func(p map[string]interface{}) string {
+			if calls.Add(1) == 1 {
+				close(entered)
+				<-release
+			}
+			return `[{"currencyCode":"EUR","rate":1.25}]`
+		},
+	})
+	c := NewCurrencyConverter(s, time.Minute)
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Rate("EUR")
+		done <- err
+	}()
+	<-entered
+
+	// the converter is not locked while rates are fetched
+	invalidated := make(chan struct{})
+	go func() {
+		c.Invalidate()
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(time.Second):
+		t.Fatal("Invalidate blocked by a rates request")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// rates requested before Invalidate are not cached
+	if v, err := c.Rate("EUR"); err != nil || v != 1.25 || calls.Load() != 2 {
+		t.Error("rates of before Invalidate cached", v, err, calls.Load())
+	}
+	if _, err := c.Rate("EUR"); err != nil || calls.Load() != 2 {
+		t.Error("rates not cached", err, calls.Load())
+	}
+}
+
+func Test_SetUsedApplication(t *testing.T) {
+	apps := `[{"appName":"bot","appId":1,"appVersions":[` +
+		`{"applicationKey":"live","delayData":false,"active":true},` +
+		`{"applicationKey":"delayed","delayData":true,"active":true}]}]`
+	var fetches int
+	s := newMethodTestServer(t, "/account/", testMethods{
+		"getDeveloperAppKeys": func(p map[string]interface{}) string {
+			fetches++
+			return apps
+		},
+		"createDeveloperAppKeys": func(p map[string]interface{}) string {
+			if p["appName"] != "bot" {
+				t.Error("app name not sent", p)
+			}
+			return `{"appName":"bot","appId":2,"appVersions":[` +
+				`{"applicationKey":"live2","delayData":false,"active":true}]}`
+		},
+	})
+
+	if err := s.SetUsedApplication("bot", true); err != nil {
+		t.Fatal(err)
+	}
+	if s.applicationKey() != "delayed" {
+		t.Error("delayed key not selected", s.applicationKey())
+	}
+	if err := s.SetUsedApplication("other", true); err == nil {
+		t.Error("unknown app selected")
+	}
+	if s.applicationKey() != "delayed" || fetches != 1 {
+		t.Error("apps not cached", fetches)
+	}
+
+	app, err := s.CreateDeveloperAppKeys("bot")
+	if err != nil || app.AppId != 2 {
+		t.Fatal("app not created", app, err)
+	}
+
+	// a second live version makes the selection ambiguous
+	apps = `[{"appName":"bot","appVersions":[` +
+		`{"applicationKey":"live","active":true},` +
+		`{"applicationKey":"live2","active":true}]}]`
+	if err := s.SetUsedApplication("bot", false); err == nil {
+		t.Error("ambiguous app selected")
+	}
+	if fetches != 2 {
+		t.Error("apps not fetched again after creation", fetches)
+	}
+}
+
+func Test_VendorOperations(t *testing.T) {
+	s := newMethodTestServer(t, "/account/", testMethods{
+		"getApplicationSubscriptionToken": func(p map[string]interface{}) string {
+			if p["subscriptionLength"] != 30.0 {
+				t.Error("subscription length not sent", p)
+			}
+			return `"sub-token"`
+		},
+		"activateApplicationSubscription": func(p map[string]interface{}) string {
+			if p["subscriptionToken"] != "sub-token" {
+				t.Error("subscription token not sent", p)
+			}
+			return `"SUCCESS"`
+		},
+		"token": func(p map[string]interface{}) string {
+			if p["grant_type"] != GrantTypeAuthorizationCode ||
+				p["code"] != "auth-code" {
+				t.Error("token params not sent", p)
+			}
+			return `{"access_token":"access","token_type":"BEARER",` +
+				`"expires_in":28800,"refresh_token":"refresh",` +
+				`"application_subscription":{"subscriptionToken":"sub-token",` +
+				`"expiryDateTime":"2024-02-01T00:00:00.000Z",` +
+				`"subscriptionStatus":"ACTIVATED","vendorClientId":"client"}}`
+		},
+	})
+
+	token, err := s.GetApplicationSubscriptionToken(30, "")
+	if err != nil || token != "sub-token" {
+		t.Fatal("subscription token not returned", token, err)
+	}
+	if err := s.ActivateApplicationSubscription(token); err != nil {
+		t.Error(err)
+	}
+	if err := s.CancelSubscriptionToken(""); err == nil {
+		t.Error("empty subscription token accepted")
+	}
+
+	info, err := s.Token(&VendorTokenRequest{
+		ClientId:     "vendor",
+		GrantType:    GrantTypeAuthorizationCode,
+		Code:         "auth-code",
+		ClientSecret: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := info.ApplicationSubscription
+	if info.AccessToken != "access" || info.ExpiresIn != 28800 ||
+		sub.VendorClientId != "client" || sub.SubscriptionToken != "sub-token" ||
+		sub.SubscriptionStatus != SubscriptionStatusActivated ||
+		!sub.ExpiryDateTime.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("access token info not decoded", info)
+	}
+	if _, err := s.Token(&VendorTokenRequest{
+		GrantType: GrantTypeRefreshToken,
+	}); err == nil {
+		t.Error("refresh without token accepted")
+	}
+}
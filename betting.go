@@ -1,8 +1,9 @@
 package betfair
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -12,6 +13,18 @@ type TimeRange struct {
 	To   time.Time `json:"to,omitempty"`
 }
 
+// omits zero bounds, encoding/json never treats time.Time as empty
+func (t TimeRange) MarshalJSON() ([]byte, error) {
+	r := make(map[string]time.Time, 2)
+	if !t.From.IsZero() {
+		r["from"] = t.From
+	}
+	if !t.To.IsZero() {
+		r["to"] = t.To
+	}
+	return json.Marshal(r)
+}
+
 type ExBestOffersOverrides struct {
 	BestPricesDepth          int     `json:"bestPricesDepth,omitempty"`
 	RollupModel              string  `json:"rollupModel,omitempty"`
@@ -45,6 +58,11 @@ type MarketFilter struct {
 	WithOrders         []string   `json:"withOrders,omitempty"`
 }
 
+// Catch-all parameters of betting operations.
+//
+// Deprecated: use the request type of the operation (ListEventsRequest,
+// ListMarketBookRequest...), fields of Query are sent whether the operation
+// knows them or not.
 type Query struct {
 	MarketFilter       *MarketFilter    `json:"filter,omitempty"`
 	Locale             string           `json:"locale,omitempty"`
@@ -59,6 +77,34 @@ type Query struct {
 	NetOfCommission    bool             `json:"netOfCommission,omitempty"`
 	PriceProjection    *PriceProjection `json:"priceProjection,omitempty"`
 	CurrencyCode       string           `json:"currencyCode,omitempty"`
+
+	// order operations
+	MarketId            string         `json:"marketId,omitempty"`
+	Instructions        Instructions   `json:"instructions,omitempty"`
+	CustomerRef         string         `json:"customerRef,omitempty"`
+	MarketVersion       *MarketVersion `json:"marketVersion,omitempty"`
+	CustomerStrategyRef string         `json:"customerStrategyRef,omitempty"`
+	Async               bool           `json:"async,omitempty"`
+
+	// order listing
+	BetIds               []string   `json:"betIds,omitempty"`
+	CustomerOrderRefs    []string   `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs []string   `json:"customerStrategyRefs,omitempty"`
+	DateRange            *TimeRange `json:"dateRange,omitempty"`
+	OrderBy              string     `json:"orderBy,omitempty"`
+	SortDir              string     `json:"sortDir,omitempty"`
+	FromRecord           int        `json:"fromRecord,omitempty"`
+	RecordCount          int        `json:"recordCount,omitempty"`
+
+	// cleared orders
+	BetStatus              string     `json:"betStatus,omitempty"`
+	EventTypeIds           []string   `json:"eventTypeIds,omitempty"`
+	EventIds               []string   `json:"eventIds,omitempty"`
+	RunnerIds              []RunnerId `json:"runnerIds,omitempty"`
+	Side                   string     `json:"side,omitempty"`
+	SettledDateRange       *TimeRange `json:"settledDateRange,omitempty"`
+	GroupBy                string     `json:"groupBy,omitempty"`
+	IncludeItemDescription bool       `json:"includeItemDescription,omitempty"`
 }
 
 // Visitor Function type
@@ -67,7 +113,8 @@ After reached result set, visitor function will be called for each item
 
 s *Session - betfair Session pointer
 
-q *Query - betfair Query pointer
+q *Query - betfair Query pointer, per-operation requests are passed as their
+Query equivalent
 
 v interface{} - Result set passed as interface
 */
@@ -166,49 +213,52 @@ type MarketBook struct {
 	CrossMatching         bool
 	RunnersVoidable       bool
 	Version               uint32
-	Runners               []struct {
-		SelectionId      uint32
-		Handicap         float64
-		Status           string
-		AdjustmentFactor float64
-		LastPriceTraded  float64
-		TotalMatched     float64
-		RemovalDate      time.Time
-		Sp               struct {
-			NearPrice         float64
-			FarPrice          float64
-			BackStakeTaken    []PriceSize
-			layLiabilityTaken []PriceSize
-			ActualSP          float64
-		}
-		Ex struct {
-			AvailableToBack []PriceSize
-			AvailableToLay  []PriceSize
-			TradedVolume    []PriceSize
-		}
-		Orders []struct {
-			PriceSize
-			BetId           string
-			OrderType       string
-			Status          string
-			PersistenceType string
-			Side            string
-			BspLiability    float64
-			PlacedDate      time.Time
-			AvgPriceMatched float64
-			SizeMatched     float64
-			SizeRemaining   float64
-			SizeLapsed      float64
-			SizeCancelled   float64
-			SizeVoided      float64
-		}
-		Matches []struct {
-			PriceSize
-			BetId     string
-			MatchId   string
-			Side      string
-			MatchDate time.Time
-		}
+	Runners               []Runner
+}
+
+// Runner of a Market Book
+type Runner struct {
+	SelectionId      uint32
+	Handicap         float64
+	Status           string
+	AdjustmentFactor float64
+	LastPriceTraded  float64
+	TotalMatched     float64
+	RemovalDate      time.Time
+	Sp               struct {
+		NearPrice         float64
+		FarPrice          float64
+		BackStakeTaken    []PriceSize
+		LayLiabilityTaken []PriceSize
+		ActualSP          float64
+	}
+	Ex struct {
+		AvailableToBack []PriceSize
+		AvailableToLay  []PriceSize
+		TradedVolume    []PriceSize
+	}
+	Orders []struct {
+		PriceSize
+		BetId           string
+		OrderType       string
+		Status          string
+		PersistenceType string
+		Side            string
+		BspLiability    float64
+		PlacedDate      time.Time
+		AvgPriceMatched float64
+		SizeMatched     float64
+		SizeRemaining   float64
+		SizeLapsed      float64
+		SizeCancelled   float64
+		SizeVoided      float64
+	}
+	Matches []struct {
+		PriceSize
+		BetId     string
+		MatchId   string
+		Side      string
+		MatchDate time.Time
 	}
 }
 
@@ -232,123 +282,186 @@ type MarketCatalogue struct {
 }
 
 // Returns event types as []EventResult or error if occured
-func (s *Session) ListEventTypes(q *Query, fn ...VisitorFunc) ([]EventTypeResult,
+func (s *Session) ListEventTypes(r Request, fn ...VisitorFunc) ([]EventTypeResult,
 	error) {
+	return s.ListEventTypesContext(context.Background(), r, fn...)
+}
+
+// Like ListEventTypes, ctx controls the request
+func (s *Session) ListEventTypesContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]EventTypeResult, error) {
 	var results []EventTypeResult
-	if err := betRequest("listEventTypes", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listEventTypes", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns country list as string or error if occured
-func (s *Session) ListCountries(q *Query, fn ...VisitorFunc) ([]CountryCodeResult,
+func (s *Session) ListCountries(r Request, fn ...VisitorFunc) ([]CountryCodeResult,
 	error) {
+	return s.ListCountriesContext(context.Background(), r, fn...)
+}
+
+// Like ListCountries, ctx controls the request
+func (s *Session) ListCountriesContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]CountryCodeResult, error) {
 	var results []CountryCodeResult
-	if err := betRequest("listCountries", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listCountries", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns events list as string or error if occured
-func (s *Session) ListEvents(q *Query, fn ...VisitorFunc) ([]EventResult, error) {
+func (s *Session) ListEvents(r Request, fn ...VisitorFunc) ([]EventResult, error) {
+	return s.ListEventsContext(context.Background(), r, fn...)
+}
+
+// Like ListEvents, ctx controls the request
+func (s *Session) ListEventsContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]EventResult, error) {
 	var results []EventResult
-	if err := betRequest("listEvents", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listEvents", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns competitions list (ie. world cop) as string or error if occured
-func (s *Session) ListCompetitions(q *Query, fn ...VisitorFunc) (
+func (s *Session) ListCompetitions(r Request, fn ...VisitorFunc) (
 	[]CompetitionResult, error) {
+	return s.ListCompetitionsContext(context.Background(), r, fn...)
+}
+
+// Like ListCompetitions, ctx controls the request
+func (s *Session) ListCompetitionsContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]CompetitionResult, error) {
 	var results []CompetitionResult
-	if err := betRequest("listCompetitions", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listCompetitions", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns a list of market types (i.e. MATCH_ODDS, NEXT_GOAL)
-func (s *Session) ListMarketTypes(q *Query, fn ...VisitorFunc) (
+func (s *Session) ListMarketTypes(r Request, fn ...VisitorFunc) (
 	[]MarketTypeResult, error) {
+	return s.ListMarketTypesContext(context.Background(), r, fn...)
+}
+
+// Like ListMarketTypes, ctx controls the request
+func (s *Session) ListMarketTypesContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]MarketTypeResult, error) {
 	var results []MarketTypeResult
-	if err := betRequest("listMarketTypes", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listMarketTypes", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns a list of Venues (i.e. Cheltenham, Ascot)
-func (s *Session) ListVenues(q *Query, fn ...VisitorFunc) ([]VenueResult,
+func (s *Session) ListVenues(r Request, fn ...VisitorFunc) ([]VenueResult,
 	error) {
+	return s.ListVenuesContext(context.Background(), r, fn...)
+}
+
+// Like ListVenues, ctx controls the request
+func (s *Session) ListVenuesContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]VenueResult, error) {
 	var results []VenueResult
-	if err := betRequest("listVenues", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listVenues", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns a list of information about published (ACTIVE/SUSPENDED) markets
-func (s *Session) ListMarketCatalogue(q *Query, fn ...VisitorFunc) (
+func (s *Session) ListMarketCatalogue(r Request, fn ...VisitorFunc) (
 	[]MarketCatalogue, error) {
+	return s.ListMarketCatalogueContext(context.Background(), r, fn...)
+}
+
+// Like ListMarketCatalogue, ctx controls the request
+func (s *Session) ListMarketCatalogueContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]MarketCatalogue, error) {
 	var results []MarketCatalogue
-	if err := betRequest("listMarketCatalogue", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listMarketCatalogue", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Returns a list of dynamic data about markets
-func (s *Session) ListMarketBook(q *Query, fn ...VisitorFunc) ([]MarketBook,
+func (s *Session) ListMarketBook(r Request, fn ...VisitorFunc) ([]MarketBook,
 	error) {
+	return s.ListMarketBookContext(context.Background(), r, fn...)
+}
+
+// Like ListMarketBook, ctx controls the request
+func (s *Session) ListMarketBookContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]MarketBook, error) {
 	var results []MarketBook
-	if err := betRequest("listMarketBook", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listMarketBook", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // Retrieve profit and loss for a given list of markets
-func (s *Session) ListMarketProfitAndLoss(q *Query, fn ...VisitorFunc) (
+func (s *Session) ListMarketProfitAndLoss(r Request, fn ...VisitorFunc) (
 	[]MarketProfitAndLoss, error) {
+	return s.ListMarketProfitAndLossContext(context.Background(), r, fn...)
+}
+
+// Like ListMarketProfitAndLoss, ctx controls the request
+func (s *Session) ListMarketProfitAndLossContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]MarketProfitAndLoss, error) {
 	var results []MarketProfitAndLoss
-	if err := betRequest("listMarketProfitAndLoss", s, q, &results, fn...); err != nil {
+	if err := betRequest(ctx, "listMarketProfitAndLoss", s, r, &results, fn...); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
 // performs betting api requests
-func betRequest(method string, s *Session, q *Query, r interface{},
-	fn ...VisitorFunc) error {
+func betRequest(ctx context.Context, method string, s *Session, q Request,
+	r interface{}, fn ...VisitorFunc) error {
 	if q == nil {
-		s.logger.Fatal("query parameter can not be nil")
-		return errors.New("query parameter can not be nil")
+		return errNilRequest(method)
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	if op := q.operation(); op != "" && op != method {
+		return fmt.Errorf("%s: unexpected request %T", method, q)
+	}
+	if v, ok := q.(*Query); ok {
+		if err := v.check(method); err != nil {
+			return err
+		}
 	}
 
 	p, err := json.Marshal(q)
 	if err != nil {
-		s.logger.Fatal(err)
-		return err
+		return fmt.Errorf("%s: encoding request: %w", method, err)
 	}
+	s.log().Debug("betting request", "method", method, "payload", string(p))
 
 	payload := strings.NewReader(string(p))
-	resp, err := doRequest(s, "betting", method, payload)
+	resp, err := doRequest(ctx, s, "betting", method, payload)
 	if err != nil {
-		s.logger.Fatal(method, err)
+		s.log().Warn("betting request failed", "method", method, "err", err)
 		return err
 	}
-	s.logger.Print(string(resp))
+	s.log().Debug("betting response", "method", method, "body", string(resp))
 
 	if err := json.Unmarshal(resp, r); err != nil {
-		s.logger.Fatal(method, err)
-		return err
+		return fmt.Errorf("%s: decoding response: %w", method, err)
 	}
 
 	for _, f := range fn {
-		f(s, q, r)
+		f(s, q.query(), r)
 	}
 
 	return nil
@@ -0,0 +1,138 @@
+package betfair
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Bet statuses of cleared orders
+const (
+	BetStatusSettled   = "SETTLED"
+	BetStatusVoided    = "VOIDED"
+	BetStatusLapsed    = "LAPSED"
+	BetStatusCancelled = "CANCELLED"
+)
+
+// Group by levels of cleared orders
+const (
+	GroupByEventType = "EVENT_TYPE"
+	GroupByEvent     = "EVENT"
+	GroupByMarket    = "MARKET"
+	GroupBySide      = "SIDE"
+	GroupByBet       = "BET"
+)
+
+// Runner Id
+type RunnerId struct {
+	MarketId    string  `json:"marketId"`
+	SelectionId uint32  `json:"selectionId"`
+	Handicap    float64 `json:"handicap,omitempty"`
+}
+
+// Item Description, set when IncludeItemDescription is requested
+type ItemDescription struct {
+	EventTypeDesc   string
+	EventDesc       string
+	MarketDesc      string
+	MarketType      string
+	MarketStartTime time.Time
+	RunnerDesc      string
+	NumberOfWinners int
+	EachWayDivisor  float64
+}
+
+// Cleared Order Summary, fields not meaningful for the requested group by
+// level are left empty
+type ClearedOrderSummary struct {
+	EventTypeId         string
+	EventId             string
+	MarketId            string
+	SelectionId         uint32
+	Handicap            float64
+	BetId               string
+	PlacedDate          time.Time
+	PersistenceType     string
+	OrderType           string
+	Side                string
+	ItemDescription     *ItemDescription
+	BetOutcome          string
+	PriceRequested      float64
+	SettledDate         time.Time
+	LastMatchedDate     time.Time
+	BetCount            int
+	Commission          float64
+	PriceMatched        float64
+	PriceReduced        bool
+	SizeSettled         float64
+	Profit              float64
+	SizeCancelled       float64
+	CustomerOrderRef    string
+	CustomerStrategyRef string
+}
+
+// Cleared Order Summary Report
+type ClearedOrderSummaryReport struct {
+	ClearedOrders []ClearedOrderSummary
+	MoreAvailable bool
+}
+
+// Returns a page of settled, voided, lapsed or cancelled orders, see
+// ListClearedOrdersRequest for filters, group by levels and paging fields.
+func (s *Session) ListClearedOrders(r Request, fn ...VisitorFunc) (
+	*ClearedOrderSummaryReport, error) {
+	return s.ListClearedOrdersContext(context.Background(), r, fn...)
+}
+
+// Like ListClearedOrders, ctx controls the request
+func (s *Session) ListClearedOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) (*ClearedOrderSummaryReport, error) {
+	var report ClearedOrderSummaryReport
+	if err := betRequest(ctx, "listClearedOrders", s, r, &report, fn...); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Returns every cleared order matching the request, requesting following pages
+// while more are available. Visitor functions are called for each page with
+// the page's query and *ClearedOrderSummaryReport.
+func (s *Session) ListAllClearedOrders(r Request, fn ...VisitorFunc) (
+	[]ClearedOrderSummary, error) {
+	return s.ListAllClearedOrdersContext(context.Background(), r, fn...)
+}
+
+// Like ListAllClearedOrders, ctx controls every page request and stops paging
+// once done
+func (s *Session) ListAllClearedOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]ClearedOrderSummary, error) {
+	if r == nil {
+		return nil, errNilRequest("listClearedOrders")
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	p, ok := r.(pagedRequest)
+	if !ok {
+		return nil, fmt.Errorf("listClearedOrders: unexpected request %T", r)
+	}
+
+	var orders []ClearedOrderSummary
+	var page Request = r
+	for from := r.query().FromRecord; ; {
+		report, err := s.ListClearedOrdersContext(ctx, page, fn...)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, report.ClearedOrders...)
+
+		if !report.MoreAvailable || len(report.ClearedOrders) == 0 {
+			return orders, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		from += len(report.ClearedOrders)
+		page = p.withFromRecord(from)
+	}
+}
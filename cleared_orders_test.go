@@ -0,0 +1,64 @@
+package betfair
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_ListAllClearedOrders(t *testing.T) {
+	var from []interface{}
+	s := newMethodTestServer(t, "/betting/", testMethods{
+		"listClearedOrders": func(p map[string]interface{}) string {
+			from = append(from, p["fromRecord"])
+			settled, _ := p["settledDateRange"].(map[string]interface{})
+			runners, _ := p["runnerIds"].([]interface{})
+			if p["betStatus"] != BetStatusSettled ||
+				p["groupBy"] != GroupByMarket || p["side"] != SideLay ||
+				p["includeItemDescription"] != true || p["locale"] != "en" ||
+				p["recordCount"] != float64(2) || len(runners) != 1 ||
+				settled["from"] != "2024-01-01T00:00:00Z" || settled["to"] != nil {
+				t.Error("query params not sent", p)
+			}
+			switch len(from) {
+			case 1:
+				return `{"clearedOrders":[{"marketId":"1.1","profit":2.5},` +
+					`{"marketId":"1.2","profit":-1}],"moreAvailable":true}`
+			case 2:
+				return `{"clearedOrders":[{"marketId":"1.3","betCount":3,` +
+					`"itemDescription":{"marketDesc":"Match Odds"}}],` +
+					`"moreAvailable":false}`
+			}
+			t.Error("requested past the last page")
+			return `{"clearedOrders":[],"moreAvailable":false}`
+		},
+	})
+
+	orders, err := s.ListAllClearedOrders(&Query{
+		BetStatus: BetStatusSettled,
+		RunnerIds: []RunnerId{{MarketId: "1.1", SelectionId: 47972}},
+		Side:      SideLay,
+		SettledDateRange: &TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		GroupBy:                GroupByMarket,
+		IncludeItemDescription: true,
+		Locale:                 "en",
+		RecordCount:            2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(from) != "[<nil> 2]" {
+		t.Error("unexpected fromRecord offsets", from)
+	}
+	if len(orders) != 3 || orders[0].Profit != 2.5 || orders[2].BetCount != 3 ||
+		orders[2].ItemDescription == nil ||
+		orders[2].ItemDescription.MarketDesc != "Match Odds" {
+		t.Error("pages not joined", orders)
+	}
+
+	if _, err := s.ListAllClearedOrders(&Query{}); err == nil {
+		t.Error("missing bet status not reported")
+	}
+}
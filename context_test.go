@@ -0,0 +1,143 @@
+package betfair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_doRequestCancel(t *testing.T) {
+	srv := newStubServer(t)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	var calls atomic.Int32
+	srv.mux.HandleFunc("/betting/listEvents/", func(w http.ResponseWriter,
+		r *http.Request) {
+		calls.Add(1)
+		// never answers while the test runs
+		<-release
+	})
+	s := srv.session(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := s.ListEventsContext(ctx, &ListEventsRequest{}); !errors.Is(err,
+		context.Canceled) {
+		t.Error("in-flight request not cancelled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("cancelled request returned late", time.Since(start))
+	}
+
+	// a cancelled ctx sends nothing
+	n := calls.Load()
+	if _, err := s.ListEventsContext(ctx, &ListEventsRequest{}); !errors.Is(err,
+		context.Canceled) {
+		t.Error("cancelled ctx not reported", err)
+	}
+	if calls.Load() != n {
+		t.Error("request sent with cancelled ctx")
+	}
+}
+
+func Test_doRequestReloginCancel(t *testing.T) {
+	srv := newStubServer(t)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	// identity stand-in where logging in again hangs for a second
+	var logins, calls atomic.Int32
+	srv.mux.HandleFunc("/slow/login", func(w http.ResponseWriter,
+		r *http.Request) {
+		if logins.Add(1) > 1 {
+			select {
+			case <-release:
+			case <-time.After(time.Second):
+			}
+		}
+		fmt.Fprint(w, `{"token":"token","status":"SUCCESS"}`)
+	})
+	srv.mux.HandleFunc("/betting/listEvents/", func(w http.ResponseWriter,
+		r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(400)
+		fmt.Fprint(w, `{"detail":{"APINGException":{"errorCode":"NO_SESSION"}}}`)
+	})
+	e := srv.endpoints()
+	e.Identity = srv.URL + "/slow/"
+	c, _ := NewInteractiveCredentials("userName", "passWord", "UK", "appKey")
+	s, err := New(context.Background(), c, WithLogger(nil), WithEndpoints(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ctx ends during the re-login, the request is not retried
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.ListEventsContext(ctx, &ListEventsRequest{}); !errors.Is(err,
+		ErrNoSession) {
+		t.Error("expired session not reported", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("re-login not bounded by ctx", time.Since(start))
+	}
+	if logins.Load() != 2 || calls.Load() != 1 {
+		t.Error("request retried", logins.Load(), calls.Load())
+	}
+}
+
+func Test_ListAllCancel(t *testing.T) {
+	for _, c := range []struct {
+		prefix, method, body string
+		list                 func(ctx context.Context, s *Session) error
+	}{
+		{"/betting/", "listCurrentOrders",
+			`{"currentOrders":[{"betId":"1"}],"moreAvailable":true}`,
+			func(ctx context.Context, s *Session) error {
+				_, err := s.ListAllCurrentOrdersContext(ctx,
+					&ListCurrentOrdersRequest{})
+				return err
+			}},
+		{"/betting/", "listClearedOrders",
+			`{"clearedOrders":[{"betId":"1"}],"moreAvailable":true}`,
+			func(ctx context.Context, s *Session) error {
+				_, err := s.ListAllClearedOrdersContext(ctx,
+					&ListClearedOrdersRequest{BetStatus: BetStatusSettled})
+				return err
+			}},
+		{"/account/", "getAccountStatement",
+			`{"accountStatement":[{"refId":"1"}],"moreAvailable":true}`,
+			func(ctx context.Context, s *Session) error {
+				_, err := s.GetAllAccountStatementContext(ctx,
+					&AccountStatementRequest{})
+				return err
+			}},
+	} {
+		// the first page says more is available and cancels ctx
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls atomic.Int32
+		body := c.body
+		s := newMethodTestServer(t, c.prefix, testMethods{
+			c.method: func(p map[string]interface{}) string {
+				calls.Add(1)
+				cancel()
+				return body
+			},
+		})
+
+		if err := c.list(ctx, s); !errors.Is(err, context.Canceled) {
+			t.Error(c.method, "cancellation not reported", err)
+		}
+		if calls.Load() != 1 {
+			t.Error(c.method, "paging not stopped", calls.Load())
+		}
+		cancel()
+	}
+}
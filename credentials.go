@@ -0,0 +1,481 @@
+package betfair
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Environment variables read by LoadCredentialsFromEnv
+const (
+	EnvUsername    = "BETFAIR_USERNAME"
+	EnvPassword    = "BETFAIR_PASSWORD"
+	EnvExchange    = "BETFAIR_EXCHANGE"
+	EnvAppKey      = "BETFAIR_APP_KEY"
+	EnvCertFile    = "BETFAIR_CERT_FILE"
+	EnvKeyFile     = "BETFAIR_KEY_FILE"
+	EnvCertPEM     = "BETFAIR_CERT_PEM"
+	EnvKeyPEM      = "BETFAIR_KEY_PEM"
+	EnvP12File     = "BETFAIR_P12_FILE"
+	EnvP12Password = "BETFAIR_P12_PASSWORD"
+	EnvTOTPSecret  = "BETFAIR_TOTP_SECRET"
+)
+
+// Credentials log a Session in. Implementations decide which identity
+// endpoint is called and how the login request and response look, so login
+// modes other than the built-in interactive and cert login can be plugged in.
+type Credentials interface {
+	// Returns endpoints used unless WithEndpoints is given
+	DefaultEndpoints() (Endpoints, error)
+	// Returns application key sent with betting and account requests
+	AppKey() string
+	// Returns the client certificate used for login, nil if none
+	ClientCertificate() (*tls.Certificate, error)
+	// Returns the login url from the session endpoints
+	LoginEndpoint(e *Endpoints) string
+	// Returns the login request posted to url
+	NewLoginRequest(ctx context.Context, url string) (*http.Request, error)
+	// Returns session token from a successful login response body
+	ParseLoginResponse(body []byte) (string, error)
+}
+
+// Deprecated: use Credentials.
+type CredentialInterface = Credentials
+
+// inherited user credentials type
+type userCredentials struct {
+	Username string
+	Password string
+	Exchange string
+}
+
+// Used for interactive login and request payload and headers. Accounts with
+// two-step authentication need AuthCode or TOTPSecret, the code is appended
+// to the password on every login.
+type InteractiveCredentials struct {
+	*userCredentials
+	ApplicationKey string
+	TOTPSecret     string                 // base32 shared secret
+	AuthCode       func() (string, error) // used instead of TOTPSecret
+}
+
+// Used for NonInteractive (cert-login). Certificate, if set, is used instead
+// of the files in CertPath. ApplicationKey is optional, SetUsedApplication or
+// WithAppKey can set it after login.
+type NonInteractiveCredentials struct {
+	*userCredentials
+	CertPath struct {
+		CrtFile string
+		KeyFile string
+	}
+	Certificate    *tls.Certificate
+	ApplicationKey string
+}
+
+// Returns interactive login credentials
+func NewInteractiveCredentials(username, password, exchange, appKey string) (
+	*InteractiveCredentials, error) {
+	if err := validateExchange(exchange); err != nil {
+		return nil, err
+	}
+
+	return &InteractiveCredentials{
+		userCredentials: &userCredentials{
+			Username: username,
+			Password: password,
+			Exchange: exchange,
+		},
+		ApplicationKey: appKey,
+	}, nil
+}
+
+// Returns cert-login credentials using the certificate and key files
+func NewCertCredentials(username, password, exchange, crtFile,
+	keyFile string) (*NonInteractiveCredentials, error) {
+	if err := validateExchange(exchange); err != nil {
+		return nil, err
+	}
+
+	c := &NonInteractiveCredentials{
+		userCredentials: &userCredentials{
+			Username: username,
+			Password: password,
+			Exchange: exchange,
+		},
+	}
+	c.CertPath.CrtFile = crtFile
+	c.CertPath.KeyFile = keyFile
+	return c, nil
+}
+
+// Deprecated: use NewInteractiveCredentials or NewCertCredentials.
+//
+// NewCredentials(username, password, exchange, appkey) returns
+// InteractiveCredentials,
+// NewCredentials(username, password, exchange, crtfile, keyfile) returns
+// NonInteractiveCredentials.
+func NewCredentials(params ...string) (Credentials, error) {
+	var (
+		c   Credentials
+		err error
+	)
+	switch len(params) {
+	case 4:
+		c, err = NewInteractiveCredentials(params[0], params[1], params[2],
+			params[3])
+	case 5:
+		c, err = NewCertCredentials(params[0], params[1], params[2], params[3],
+			params[4])
+	default:
+		err = errors.New("invalid credential params")
+	}
+
+	// avoid returning a typed nil
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Returns built-in endpoints of the exchange
+func (u *userCredentials) DefaultEndpoints() (Endpoints, error) {
+	return ExchangeEndpoints(u.Exchange)
+}
+
+// returns the login form payload, code is appended to the password
+func (u *userCredentials) loginForm(code string) string {
+	return url.Values{
+		"username": {u.Username},
+		"password": {u.Password + code},
+	}.Encode()
+}
+
+// returns a form post to url with X-Application set to appKey
+func newLoginRequest(ctx context.Context, url, appKey, form string) (
+	*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url,
+		strings.NewReader(form))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Application", appKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+func (c *InteractiveCredentials) AppKey() string {
+	return c.ApplicationKey
+}
+
+// Returns nil, interactive login uses no client certificate
+func (c *InteractiveCredentials) ClientCertificate() (*tls.Certificate,
+	error) {
+	return nil, nil
+}
+
+func (c *InteractiveCredentials) LoginEndpoint(e *Endpoints) string {
+	return e.Identity + "login"
+}
+
+func (c *InteractiveCredentials) NewLoginRequest(ctx context.Context,
+	url string) (*http.Request, error) {
+	code, err := c.authCode()
+	if err != nil {
+		return nil, err
+	}
+	return newLoginRequest(ctx, url, c.ApplicationKey, c.loginForm(code))
+}
+
+// returns the two-step authentication code, empty if none is configured
+func (c *InteractiveCredentials) authCode() (string, error) {
+	switch {
+	case c.AuthCode != nil:
+		return c.AuthCode()
+	case c.TOTPSecret != "":
+		return TOTP(c.TOTPSecret, time.Now())
+	}
+	return "", nil
+}
+
+func (c *InteractiveCredentials) ParseLoginResponse(body []byte) (string,
+	error) {
+	var result struct {
+		Token   string
+		Product string
+		Status  string
+		Error   string
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("login: decoding response: %w", err)
+	}
+
+	if result.Status != "SUCCESS" {
+		return "", &LoginError{
+			Method: "login",
+			Status: result.Status,
+			Code:   LoginStatus(result.Error),
+		}
+	}
+	return result.Token, nil
+}
+
+func (c *NonInteractiveCredentials) AppKey() string {
+	return c.ApplicationKey
+}
+
+func (c *NonInteractiveCredentials) ClientCertificate() (*tls.Certificate,
+	error) {
+	cert, err := c.certificate()
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func (c *NonInteractiveCredentials) LoginEndpoint(e *Endpoints) string {
+	return e.CertIdentity + "certlogin"
+}
+
+// Returns the certlogin request, X-Application is the package name since
+// cert login needs no application key
+func (c *NonInteractiveCredentials) NewLoginRequest(ctx context.Context,
+	url string) (*http.Request, error) {
+	return newLoginRequest(ctx, url, PKG_NAME, c.loginForm(""))
+}
+
+func (c *NonInteractiveCredentials) ParseLoginResponse(body []byte) (string,
+	error) {
+	var result struct {
+		Token  string `json:"sessionToken"`
+		Status string `json:"loginStatus"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("certlogin: decoding response: %w", err)
+	}
+
+	if result.Status != "SUCCESS" {
+		return "", &LoginError{
+			Method: "certlogin",
+			Status: result.Status,
+			Code:   LoginStatus(result.Status),
+		}
+	}
+	return result.Token, nil
+}
+
+// Returns cert-login credentials using the PEM encoded certificate and key
+func NewCertCredentialsFromPEM(username, password, exchange string, certPEM,
+	keyPEM []byte) (*NonInteractiveCredentials, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return NewCertCredentialsFromTLS(username, password, exchange, cert)
+}
+
+// Returns cert-login credentials using an already loaded certificate
+func NewCertCredentialsFromTLS(username, password, exchange string,
+	cert tls.Certificate) (*NonInteractiveCredentials, error) {
+	if err := validateExchange(exchange); err != nil {
+		return nil, err
+	}
+	if len(cert.Certificate) == 0 || cert.PrivateKey == nil {
+		return nil, errors.New("certificate and private key are required")
+	}
+
+	return &NonInteractiveCredentials{
+		userCredentials: &userCredentials{
+			Username: username,
+			Password: password,
+			Exchange: exchange,
+		},
+		Certificate: &cert,
+	}, nil
+}
+
+// Returns cert-login credentials using a PKCS#12 (.p12, .pfx) bundle
+// protected by p12Password. Certificates of the bundle other than the one
+// matching the private key are kept as its chain. Bundles exported with the
+// AES/PBKDF2 defaults of OpenSSL 3 and legacy RC2/3DES ones are accepted.
+func NewCertCredentialsFromPKCS12(username, password, exchange string,
+	p12 []byte, p12Password string) (*NonInteractiveCredentials, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(p12, p12Password)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := pkcs12KeyPair(key, append([]*x509.Certificate{leaf},
+		chain...))
+	if err != nil {
+		return nil, err
+	}
+	return NewCertCredentialsFromTLS(username, password, exchange, cert)
+}
+
+// returns the key pair of a PKCS#12 bundle. The decoder takes the first
+// certificate bag as the leaf while bundles may list CA certificates first,
+// so the leaf is the certificate matching the key.
+func pkcs12KeyPair(key interface{}, certs []*x509.Certificate) (
+	tls.Certificate, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("pkcs12: unsupported private "+
+			"key %T", key)
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("pkcs12: unsupported public "+
+			"key %T", signer.Public())
+	}
+
+	for i, leaf := range certs {
+		if !pub.Equal(leaf.PublicKey) {
+			continue
+		}
+		cert := tls.Certificate{
+			Certificate: [][]byte{leaf.Raw},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		}
+		for j, c := range certs {
+			if j != i {
+				cert.Certificate = append(cert.Certificate, c.Raw)
+			}
+		}
+		return cert, nil
+	}
+	return tls.Certificate{}, errors.New(
+		"pkcs12: no certificate matches the private key")
+}
+
+// Credentials read from environment or a config file. Cert login is used
+// if a certificate is given in any form, interactive login otherwise.
+type credentialsConfig struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Exchange    string `json:"exchange"`
+	AppKey      string `json:"appKey"`
+	CertFile    string `json:"certFile"`
+	KeyFile     string `json:"keyFile"`
+	CertPEM     string `json:"certPEM"`
+	KeyPEM      string `json:"keyPEM"`
+	P12File     string `json:"p12File"`
+	P12Password string `json:"p12Password"`
+	TOTPSecret  string `json:"totpSecret"`
+}
+
+// Returns credentials from BETFAIR_* environment variables (see EnvUsername
+// and others), exchange defaults to UK
+func LoadCredentialsFromEnv() (Credentials, error) {
+	return credentialsConfig{
+		Username:    os.Getenv(EnvUsername),
+		Password:    os.Getenv(EnvPassword),
+		Exchange:    os.Getenv(EnvExchange),
+		AppKey:      os.Getenv(EnvAppKey),
+		CertFile:    os.Getenv(EnvCertFile),
+		KeyFile:     os.Getenv(EnvKeyFile),
+		CertPEM:     os.Getenv(EnvCertPEM),
+		KeyPEM:      os.Getenv(EnvKeyPEM),
+		P12File:     os.Getenv(EnvP12File),
+		P12Password: os.Getenv(EnvP12Password),
+		TOTPSecret:  os.Getenv(EnvTOTPSecret),
+	}.credentials()
+}
+
+// Returns credentials from a json file with username, password, exchange,
+// appKey and optionally totpSecret, certFile/keyFile, certPEM/keyPEM or
+// p12File/p12Password keys, exchange defaults to UK
+func LoadCredentialsFromFile(path string) (Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c credentialsConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c.credentials()
+}
+
+func (c credentialsConfig) credentials() (Credentials, error) {
+	if c.Username == "" || c.Password == "" {
+		return nil, errors.New("username and password are required")
+	}
+	if c.Exchange == "" {
+		c.Exchange = ExchangeUK
+	}
+
+	var (
+		cert *NonInteractiveCredentials
+		err  error
+	)
+	switch {
+	case c.P12File != "":
+		var p12 []byte
+		if p12, err = os.ReadFile(c.P12File); err != nil {
+			return nil, err
+		}
+		cert, err = NewCertCredentialsFromPKCS12(c.Username, c.Password,
+			c.Exchange, p12, c.P12Password)
+	case c.CertPEM != "" || c.KeyPEM != "":
+		cert, err = NewCertCredentialsFromPEM(c.Username, c.Password,
+			c.Exchange, []byte(c.CertPEM), []byte(c.KeyPEM))
+	case c.CertFile != "" || c.KeyFile != "":
+		cert, err = NewCertCredentials(c.Username, c.Password, c.Exchange,
+			c.CertFile, c.KeyFile)
+	default:
+		if c.AppKey == "" {
+			return nil, errors.New("application key is required for " +
+				"interactive login")
+		}
+		v, err := NewInteractiveCredentials(c.Username, c.Password,
+			c.Exchange, c.AppKey)
+		if err != nil {
+			return nil, err
+		}
+		v.TOTPSecret = c.TOTPSecret
+		return v, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	cert.ApplicationKey = c.AppKey
+	return cert, nil
+}
+
+// returns the client certificate, loaded from CertPath unless Certificate
+// is set
+func (c *NonInteractiveCredentials) certificate() (tls.Certificate, error) {
+	if c.Certificate != nil {
+		return *c.Certificate, nil
+	}
+
+	// check crt and key file exists
+	if _, err := os.Stat(c.CertPath.CrtFile); os.IsNotExist(err) {
+		return tls.Certificate{}, err
+	}
+
+	if _, err := os.Stat(c.CertPath.KeyFile); os.IsNotExist(err) {
+		return tls.Certificate{}, err
+	}
+
+	// load key
+	return tls.LoadX509KeyPair(c.CertPath.CrtFile, c.CertPath.KeyFile)
+}
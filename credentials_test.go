@@ -0,0 +1,237 @@
+package betfair
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func Test_NewCertCredentialsFromPEM(t *testing.T) {
+	crt, key := writeTestCert(t)
+	certPEM, _ := os.ReadFile(crt)
+	keyPEM, _ := os.ReadFile(key)
+
+	c, err := NewCertCredentialsFromPEM("userName", "passWord", "UK", certPEM,
+		keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Certificate == nil || c.Username != "userName" {
+		t.Error("pem credential errors")
+	}
+
+	if _, err := c.certificate(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewCertCredentialsFromPEM("userName", "passWord", "UK",
+		keyPEM, certPEM); err == nil {
+		t.Error("invalid pem accepted")
+	}
+}
+
+// returns common names of the certificates of c, leaf first
+func certNames(t *testing.T, c [][]byte) []string {
+	var names []string
+	for _, der := range c {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+// testdata/client.p12 and client-aes.p12 hold a client certificate issued
+// by a test CA, the CA certificate and the client key, made with
+//
+//	openssl pkcs12 -export -legacy -in leaf.crt -inkey leaf.key \
+//		-certfile ca.crt -passout pass:secret -out client.p12
+//
+// and the same without -legacy (AES-256, PBKDF2) for client-aes.p12.
+func Test_NewCertCredentialsFromPKCS12(t *testing.T) {
+	for _, name := range []string{"client.p12", "client-aes.p12"} {
+		p12, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := NewCertCredentialsFromPKCS12("userName", "passWord", "UK",
+			p12, "secret")
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		names := certNames(t, c.Certificate.Certificate)
+		if len(names) != 2 || names[0] != "betfair test client" ||
+			names[1] != "betfair test CA" {
+			t.Error(name, "unexpected certificate chain", names)
+		}
+
+		if _, err := NewCertCredentialsFromPKCS12("userName", "passWord", "UK",
+			p12, "wrong"); err == nil {
+			t.Error(name, "wrong bundle password accepted")
+		}
+	}
+}
+
+func Test_pkcs12KeyPair(t *testing.T) {
+	p12, err := os.ReadFile(filepath.Join("testdata", "client-aes.p12"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, leaf, chain, err := pkcs12.DecodeChain(p12, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 1 {
+		t.Fatal("unexpected chain", len(chain))
+	}
+
+	// a CA listed first must not become the leaf
+	cert, err := pkcs12KeyPair(key, []*x509.Certificate{chain[0], leaf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := certNames(t, cert.Certificate)
+	if len(names) != 2 || names[0] != "betfair test client" ||
+		names[1] != "betfair test CA" || cert.Leaf != leaf {
+		t.Error("leaf not moved first", names)
+	}
+
+	if _, err := pkcs12KeyPair(key, chain); err == nil {
+		t.Error("certificate not matching the key accepted")
+	}
+	if _, err := pkcs12KeyPair(nil, []*x509.Certificate{leaf}); err == nil {
+		t.Error("bundle without key accepted")
+	}
+}
+
+func Test_LoadCredentialsFromEnv(t *testing.T) {
+	t.Setenv(EnvUsername, "userName")
+	t.Setenv(EnvPassword, "passWord")
+	t.Setenv(EnvAppKey, "appKey")
+
+	c, err := LoadCredentialsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := c.(*InteractiveCredentials)
+	if u.ApplicationKey != "appKey" || u.Exchange != "UK" {
+		t.Error("interactive env credential errors")
+	}
+
+	crt, key := writeTestCert(t)
+	t.Setenv(EnvCertFile, crt)
+	t.Setenv(EnvKeyFile, key)
+	c, err = LoadCredentialsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := c.(*NonInteractiveCredentials)
+	if v.ApplicationKey != "appKey" || v.CertPath.CrtFile != crt {
+		t.Error("cert env credential errors")
+	}
+}
+
+func Test_LoadCredentialsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "betfair.json")
+	err := os.WriteFile(path, []byte(`{"username":"userName",`+
+		`"password":"passWord","exchange":"AU","appKey":"appKey"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadCredentialsFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := c.(*InteractiveCredentials); u.Exchange != "AU" {
+		t.Error("file credential errors")
+	}
+}
+
+func Test_NewCertCredentials(t *testing.T) {
+	c, err := NewCertCredentials("userName", "passWord", "UK", "crt", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := ExchangeEndpoints("UK")
+	if c.LoginEndpoint(&e) != e.CertIdentity+"certlogin" {
+		t.Error("cert login endpoint error")
+	}
+
+	token, err := c.ParseLoginResponse([]byte(
+		`{"sessionToken":"token","loginStatus":"SUCCESS"}`))
+	if err != nil || token != "token" {
+		t.Error("cert login response error", token, err)
+	}
+
+	_, err = c.ParseLoginResponse([]byte(
+		`{"loginStatus":"INVALID_USERNAME_OR_PASSWORD"}`))
+	if !errors.Is(err, LoginInvalidUsernameOrPassword) {
+		t.Error("failed cert login accepted", err)
+	}
+
+	if _, err := NewCertCredentials("userName", "passWord", "XX", "crt",
+		"key"); err == nil {
+		t.Error("unknown exchange accepted")
+	}
+}
+
+func Test_InteractiveLoginRequest(t *testing.T) {
+	c, _ := NewInteractiveCredentials("userName", "p&ss=w+rd", "UK", "appKey")
+	c.AuthCode = func() (string, error) { return "123456", nil }
+
+	req, err := c.NewLoginRequest(context.Background(), "http://127.0.0.1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	if req.PostForm.Get("username") != "userName" ||
+		req.PostForm.Get("password") != "p&ss=w+rd123456" {
+		t.Error("login form not encoded", req.PostForm)
+	}
+	if req.Header.Get("X-Application") != "appKey" {
+		t.Error("application key not sent")
+	}
+
+	_, err = c.ParseLoginResponse([]byte(`{"token":"","product":"appKey",` +
+		`"status":"FAIL","error":"STRONG_AUTH_CODE_REQUIRED"}`))
+	var e *LoginError
+	if !errors.Is(err, LoginStrongAuthCodeRequired) || !errors.As(err, &e) ||
+		e.Status != "FAIL" {
+		t.Error("login status not reported", err)
+	}
+}
+
+func Test_TOTP(t *testing.T) {
+	// RFC 6238 SHA1 test secret "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		2000000000: "279037",
+	} {
+		code, err := TOTP(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != want {
+			t.Errorf("%d: got %s want %s", unix, code, want)
+		}
+	}
+
+	if _, err := TOTP("not base32!", time.Now()); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
@@ -0,0 +1,176 @@
+package betfair
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Currency of Betfair rates and minimum stakes
+const CurrencyGBP = "GBP"
+
+// TTL used by NewCurrencyConverter when none is given
+const DefaultCurrencyRateTTL = time.Hour
+
+// Minimum stake of a bet in GBP, other currencies have the equivalent amount
+// at current rates as minimum, see CurrencyConverter.MinimumStake
+const MinimumStakeGBP = 1.0
+
+// Currency Rate, units of CurrencyCode per unit of the from currency
+type CurrencyRate struct {
+	CurrencyCode string
+	Rate         float64
+}
+
+// Returns rates from GBP to every currency
+func (s *Session) ListCurrencyRates() ([]CurrencyRate, error) {
+	return s.ListCurrencyRatesContext(context.Background())
+}
+
+// Like ListCurrencyRates, ctx controls the request
+func (s *Session) ListCurrencyRatesContext(ctx context.Context) (
+	[]CurrencyRate, error) {
+	params := struct {
+		FromCurrency string `json:"fromCurrency"`
+	}{CurrencyGBP}
+
+	var rates []CurrencyRate
+	err := accountRequest(ctx, "listCurrencyRates", s, params, &rates)
+	return rates, err
+}
+
+// CurrencyConverter converts stakes and profits between currencies through
+// GBP rates of ListCurrencyRates. Rates are fetched on first use and again
+// once older than the TTL. It is safe for concurrent use.
+type CurrencyConverter struct {
+	s       *Session
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	rates   map[string]float64 // never modified once set
+	fetched time.Time
+	gen     int // incremented by Invalidate
+}
+
+// Returns a converter using rates of s, DefaultCurrencyRateTTL is used if
+// ttl is not positive
+func NewCurrencyConverter(s *Session, ttl time.Duration) *CurrencyConverter {
+	if ttl <= 0 {
+		ttl = DefaultCurrencyRateTTL
+	}
+	return &CurrencyConverter{s: s, ttl: ttl, now: time.Now}
+}
+
+// Returns units of currency per GBP
+func (c *CurrencyConverter) Rate(currency string) (float64, error) {
+	return c.RateContext(context.Background(), currency)
+}
+
+// Like Rate, ctx controls the rates request if one is needed
+func (c *CurrencyConverter) RateContext(ctx context.Context,
+	currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == CurrencyGBP {
+		return 1, nil
+	}
+
+	rates, err := c.currentRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rate, ok := rates[currency]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no rate for currency %q", currency)
+	}
+	return rate, nil
+}
+
+// Returns amount of from currency in to currency
+func (c *CurrencyConverter) Convert(amount float64, from, to string) (
+	float64, error) {
+	return c.ConvertContext(context.Background(), amount, from, to)
+}
+
+// Like Convert, ctx controls the rates request if one is needed
+func (c *CurrencyConverter) ConvertContext(ctx context.Context,
+	amount float64, from, to string) (float64, error) {
+	fromRate, err := c.RateContext(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := c.RateContext(ctx, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount / fromRate * toRate, nil
+}
+
+// Returns amount of GBP in currency, e.g. the minimum stake of an account
+func (c *CurrencyConverter) FromGBP(amount float64, currency string) (
+	float64, error) {
+	return c.Convert(amount, CurrencyGBP, currency)
+}
+
+// Returns amount of currency in GBP
+func (c *CurrencyConverter) ToGBP(amount float64, currency string) (
+	float64, error) {
+	return c.Convert(amount, currency, CurrencyGBP)
+}
+
+// Returns the minimum stake of a bet placed in currency, MinimumStakeGBP
+// converted and rounded up to whole cents
+func (c *CurrencyConverter) MinimumStake(currency string) (float64, error) {
+	return c.MinimumStakeContext(context.Background(), currency)
+}
+
+// Like MinimumStake, ctx controls the rates request if one is needed
+func (c *CurrencyConverter) MinimumStakeContext(ctx context.Context,
+	currency string) (float64, error) {
+	v, err := c.ConvertContext(ctx, MinimumStakeGBP, CurrencyGBP, currency)
+	if err != nil {
+		return 0, err
+	}
+	// the epsilon keeps float error from rounding an exact cent up
+	return math.Ceil(v*100-1e-9) / 100, nil
+}
+
+// Drops cached rates so the next conversion fetches them again
+func (c *CurrencyConverter) Invalidate() {
+	c.mu.Lock()
+	c.rates = nil
+	c.gen++
+	c.mu.Unlock()
+}
+
+// returns cached rates or fetches them if missing or expired. c.mu is not
+// held during the request, so a slow fetch does not block other callers.
+func (c *CurrencyConverter) currentRates(ctx context.Context) (
+	map[string]float64, error) {
+	c.mu.Lock()
+	rates, fetched, gen := c.rates, c.fetched, c.gen
+	c.mu.Unlock()
+	if rates != nil && c.now().Sub(fetched) < c.ttl {
+		return rates, nil
+	}
+
+	list, err := c.s.ListCurrencyRatesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates = make(map[string]float64, len(list))
+	for _, r := range list {
+		rates[strings.ToUpper(r.CurrencyCode)] = r.Rate
+	}
+
+	// rates fetched before an Invalidate are used but not cached
+	c.mu.Lock()
+	if c.gen == gen {
+		c.rates, c.fetched = rates, c.now()
+	}
+	c.mu.Unlock()
+	return rates, nil
+}
@@ -0,0 +1,122 @@
+package betfair
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Order projections
+const (
+	OrderProjectionAll               = "ALL"
+	OrderProjectionExecutable        = "EXECUTABLE"
+	OrderProjectionExecutionComplete = "EXECUTION_COMPLETE"
+)
+
+// Order by values
+const (
+	OrderByBet         = "BY_BET"
+	OrderByMarket      = "BY_MARKET"
+	OrderByMatchTime   = "BY_MATCH_TIME"
+	OrderByPlaceTime   = "BY_PLACE_TIME"
+	OrderBySettledTime = "BY_SETTLED_TIME"
+	OrderByVoidTime    = "BY_VOID_TIME"
+)
+
+// Sort directions
+const (
+	SortDirEarliestToLatest = "EARLIEST_TO_LATEST"
+	SortDirLatestToEarliest = "LATEST_TO_EARLIEST"
+)
+
+// Current Order Summary
+type CurrentOrderSummary struct {
+	BetId               string
+	MarketId            string
+	SelectionId         uint32
+	Handicap            float64
+	PriceSize           PriceSize
+	BspLiability        float64
+	Side                string
+	Status              string
+	PersistenceType     string
+	OrderType           string
+	PlacedDate          time.Time
+	MatchedDate         time.Time
+	AveragePriceMatched float64
+	SizeMatched         float64
+	SizeRemaining       float64
+	SizeLapsed          float64
+	SizeCancelled       float64
+	SizeVoided          float64
+	RegulatorAuthCode   string
+	RegulatorCode       string
+	CustomerOrderRef    string
+	CustomerStrategyRef string
+}
+
+// Current Order Summary Report
+type CurrentOrderSummaryReport struct {
+	CurrentOrders []CurrentOrderSummary
+	MoreAvailable bool
+}
+
+// Returns a page of current orders, see ListCurrentOrdersRequest for filters
+// and paging fields.
+func (s *Session) ListCurrentOrders(r Request, fn ...VisitorFunc) (
+	*CurrentOrderSummaryReport, error) {
+	return s.ListCurrentOrdersContext(context.Background(), r, fn...)
+}
+
+// Like ListCurrentOrders, ctx controls the request
+func (s *Session) ListCurrentOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) (*CurrentOrderSummaryReport, error) {
+	var report CurrentOrderSummaryReport
+	if err := betRequest(ctx, "listCurrentOrders", s, r, &report, fn...); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Returns every current order matching the request, requesting following pages
+// while more are available. Visitor functions are called for each page with
+// the page's query and *CurrentOrderSummaryReport.
+func (s *Session) ListAllCurrentOrders(r Request, fn ...VisitorFunc) (
+	[]CurrentOrderSummary, error) {
+	return s.ListAllCurrentOrdersContext(context.Background(), r, fn...)
+}
+
+// Like ListAllCurrentOrders, ctx controls every page request and stops paging
+// once done
+func (s *Session) ListAllCurrentOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) ([]CurrentOrderSummary, error) {
+	if r == nil {
+		return nil, errNilRequest("listCurrentOrders")
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	p, ok := r.(pagedRequest)
+	if !ok {
+		return nil, fmt.Errorf("listCurrentOrders: unexpected request %T", r)
+	}
+
+	var orders []CurrentOrderSummary
+	var page Request = r
+	for from := r.query().FromRecord; ; {
+		report, err := s.ListCurrentOrdersContext(ctx, page, fn...)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, report.CurrentOrders...)
+
+		if !report.MoreAvailable || len(report.CurrentOrders) == 0 {
+			return orders, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		from += len(report.CurrentOrders)
+		page = p.withFromRecord(from)
+	}
+}
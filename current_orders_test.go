@@ -0,0 +1,99 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_ListAllCurrentOrders(t *testing.T) {
+	var from []interface{}
+	s := newMethodTestServer(t, "/betting/", testMethods{
+		"listCurrentOrders": func(p map[string]interface{}) string {
+			from = append(from, p["fromRecord"])
+			if p["recordCount"] != float64(2) ||
+				p["orderProjection"] != OrderProjectionExecutable {
+				t.Error("page params not kept", p)
+			}
+			switch len(from) {
+			case 1:
+				return `{"currentOrders":[{"betId":"1"},{"betId":"2"}],` +
+					`"moreAvailable":true}`
+			case 2:
+				return `{"currentOrders":[{"betId":"3"},{"betId":"4"}],` +
+					`"moreAvailable":true}`
+			case 3:
+				return `{"currentOrders":[{"betId":"5"}],"moreAvailable":false}`
+			}
+			t.Error("requested past the last page")
+			return `{"currentOrders":[],"moreAvailable":false}`
+		},
+	})
+
+	var pages int
+	orders, err := s.ListAllCurrentOrders(&ListCurrentOrdersRequest{
+		OrderProjection: OrderProjectionExecutable,
+		RecordCount:     2,
+	}, func(s *Session, q *Query, r interface{}) {
+		pages++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(from) != "[<nil> 2 4]" {
+		t.Error("unexpected fromRecord offsets", from)
+	}
+	if pages != 3 || len(orders) != 5 || orders[4].BetId != "5" {
+		t.Error("pages not joined", pages, orders)
+	}
+}
+
+func Test_ListAllCurrentOrdersEmptyPage(t *testing.T) {
+	var calls int
+	s := newMethodTestServer(t, "/betting/", testMethods{
+		"listCurrentOrders": func(p map[string]interface{}) string {
+			calls++
+			if calls == 1 {
+				if p["fromRecord"] != float64(10) {
+					t.Error("first record not sent", p)
+				}
+				return `{"currentOrders":[{"betId":"1"}],"moreAvailable":true}`
+			}
+			// more available but nothing returned must not loop forever
+			return `{"currentOrders":[],"moreAvailable":true}`
+		},
+	})
+
+	orders, err := s.ListAllCurrentOrders(&Query{FromRecord: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(orders) != 1 {
+		t.Error("paging did not stop on an empty page", calls, orders)
+	}
+}
+
+func Test_TimeRangeMarshal(t *testing.T) {
+	from := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		f    MarketFilter
+		want string
+	}{
+		{MarketFilter{MarketStartTime: &TimeRange{}},
+			`{"marketStartTime":{}}`},
+		{MarketFilter{MarketStartTime: &TimeRange{From: from}},
+			`{"marketStartTime":{"from":"2024-01-02T10:00:00Z"}}`},
+		{MarketFilter{MarketStartTime: &TimeRange{To: from}},
+			`{"marketStartTime":{"to":"2024-01-02T10:00:00Z"}}`},
+	} {
+		p, err := json.Marshal(&c.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != c.want {
+			t.Errorf("unexpected payload %s, want %s", p, c.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package betfair
+
+import (
+	"fmt"
+)
+
+// Exchanges (jurisdictions) with built-in endpoints
+const (
+	ExchangeUK = "UK"
+	ExchangeAU = "AU"
+	ExchangeIT = "IT"
+	ExchangeES = "ES"
+	ExchangeRO = "RO"
+)
+
+// Endpoints is the set of hosts a Session talks to. Identity, CertIdentity,
+// Betting and Account are base URLs the operation name is appended to,
+// Stream is the host:port of the Exchange Stream API.
+//
+// A custom set passed with WithEndpoints lets the client target a local mock
+// or a recording proxy.
+type Endpoints struct {
+	Identity     string // login, keepAlive and logout
+	CertIdentity string // certlogin
+	Betting      string
+	Account      string
+	Heartbeat    string
+	RaceStatus   string
+	Stream       string
+}
+
+var endpoints = map[string]Endpoints{
+	ExchangeUK: {
+		Identity:     "https://identitysso.betfair.com/api/",
+		CertIdentity: "https://identitysso-api.betfair.com/api/",
+		Betting:      "https://api.betfair.com/exchange/betting/rest/v1.0/",
+		Account:      "https://api.betfair.com/exchange/account/rest/v1.0/",
+		Heartbeat:    "https://api.betfair.com/exchange/heartbeat/json-rpc/v1",
+		RaceStatus:   "https://api.betfair.com/exchange/scores/json-rpc/v1",
+		Stream:       "stream-api.betfair.com:443",
+	},
+	ExchangeAU: {
+		Identity:     "https://identitysso.betfair.com/api/",
+		CertIdentity: "https://identitysso-api.betfair.com/api/",
+		Betting:      "https://api-au.betfair.com/exchange/betting/rest/v1.0/",
+		Account:      "https://api-au.betfair.com/exchange/account/rest/v1.0/",
+		Heartbeat:    "https://api-au.betfair.com/exchange/heartbeat/json-rpc/v1",
+		RaceStatus:   "https://api.betfair.com/exchange/scores/json-rpc/v1",
+		Stream:       "stream-api.betfair.com:443",
+	},
+	ExchangeIT: {
+		Identity:     "https://identitysso.betfair.it/api/",
+		CertIdentity: "https://identitysso-cert.betfair.it/api/",
+		Betting:      "https://api.betfair.it/exchange/betting/rest/v1.0/",
+		Account:      "https://api.betfair.it/exchange/account/rest/v1.0/",
+		Heartbeat:    "https://api.betfair.it/exchange/heartbeat/json-rpc/v1",
+		RaceStatus:   "https://api.betfair.it/exchange/scores/json-rpc/v1",
+		Stream:       "stream-api.betfair.it:443",
+	},
+	ExchangeES: {
+		Identity:     "https://identitysso.betfair.es/api/",
+		CertIdentity: "https://identitysso-cert.betfair.es/api/",
+		Betting:      "https://api.betfair.es/exchange/betting/rest/v1.0/",
+		Account:      "https://api.betfair.es/exchange/account/rest/v1.0/",
+		Heartbeat:    "https://api.betfair.es/exchange/heartbeat/json-rpc/v1",
+		RaceStatus:   "https://api.betfair.es/exchange/scores/json-rpc/v1",
+		Stream:       "stream-api.betfair.es:443",
+	},
+	ExchangeRO: {
+		Identity:     "https://identitysso.betfair.ro/api/",
+		CertIdentity: "https://identitysso-cert.betfair.ro/api/",
+		Betting:      "https://api.betfair.ro/exchange/betting/rest/v1.0/",
+		Account:      "https://api.betfair.ro/exchange/account/rest/v1.0/",
+		Heartbeat:    "https://api.betfair.ro/exchange/heartbeat/json-rpc/v1",
+		RaceStatus:   "https://api.betfair.ro/exchange/scores/json-rpc/v1",
+		Stream:       "stream-api.betfair.ro:443",
+	},
+}
+
+// Returns built-in endpoints of exchange, a copy callers may modify
+func ExchangeEndpoints(exchange string) (Endpoints, error) {
+	e, ok := endpoints[exchange]
+	if !ok {
+		return Endpoints{}, fmt.Errorf("unknown exchange %q", exchange)
+	}
+	return e, nil
+}
+
+// checks exchange has built-in endpoints
+func validateExchange(exchange string) error {
+	_, err := ExchangeEndpoints(exchange)
+	return err
+}
+
+// prepares betfair endpoint url of the method
+func prepareEndpoint(e *Endpoints, endpoint, method string) (string, error) {
+	var base, path string
+	switch endpoint {
+	case "keepAlive", "logout":
+		base, path = e.Identity, endpoint
+	case "betting":
+		base, path = e.Betting, method+"/"
+	case "account":
+		base, path = e.Account, method+"/"
+	}
+
+	if base == "" {
+		return "", fmt.Errorf("invalid endpoint params: %s %s", endpoint, method)
+	}
+	return base + path, nil
+}
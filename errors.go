@@ -0,0 +1,263 @@
+package betfair
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrorCode is the errorCode of a betting or account API exception. Codes are
+// errors themselves so they can be matched with errors.Is:
+//
+//	if errors.Is(err, betfair.ErrInvalidSessionInformation) { ... }
+type ErrorCode string
+
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
+// Error codes of APINGException (betting), AccountAPINGException (account)
+// and stream status messages
+const (
+	ErrTooMuchData               ErrorCode = "TOO_MUCH_DATA"
+	ErrInvalidInputData          ErrorCode = "INVALID_INPUT_DATA"
+	ErrInvalidSessionInformation ErrorCode = "INVALID_SESSION_INFORMATION"
+	ErrNoAppKey                  ErrorCode = "NO_APP_KEY"
+	ErrNoSession                 ErrorCode = "NO_SESSION"
+	ErrUnexpectedError           ErrorCode = "UNEXPECTED_ERROR"
+	ErrInvalidAppKey             ErrorCode = "INVALID_APP_KEY"
+	ErrTooManyRequests           ErrorCode = "TOO_MANY_REQUESTS"
+	ErrServiceBusy               ErrorCode = "SERVICE_BUSY"
+	ErrTimeoutError              ErrorCode = "TIMEOUT_ERROR"
+	ErrRequestSizeExceedsLimit   ErrorCode = "REQUEST_SIZE_EXCEEDS_LIMIT"
+	ErrAccessDenied              ErrorCode = "ACCESS_DENIED"
+
+	// account only
+	ErrDuplicateAppName          ErrorCode = "DUPLICATE_APP_NAME"
+	ErrAppKeyCreationFailed      ErrorCode = "APP_KEY_CREATION_FAILED"
+	ErrAppCreationFailed         ErrorCode = "APP_CREATION_FAILED"
+	ErrSubscriptionExpired       ErrorCode = "SUBSCRIPTION_EXPIRED"
+	ErrInvalidSubscriptionToken  ErrorCode = "INVALID_SUBSCRIPTION_TOKEN"
+	ErrInvalidClientRef          ErrorCode = "INVALID_CLIENT_REF"
+	ErrWalletNotFound            ErrorCode = "WALLET_NOT_FOUND"
+	ErrUnauthorized              ErrorCode = "UNAUTHORIZED"
+	ErrNotFound                  ErrorCode = "NOT_FOUND"
+	ErrInvalidVendorClientAccess ErrorCode = "INVALID_VENDOR_CLIENT_ACCESS"
+
+	// stream only
+	ErrInvalidInput               ErrorCode = "INVALID_INPUT"
+	ErrTimeout                    ErrorCode = "TIMEOUT"
+	ErrNotAuthorized              ErrorCode = "NOT_AUTHORIZED"
+	ErrInvalidClock               ErrorCode = "INVALID_CLOCK"
+	ErrInvalidRequest             ErrorCode = "INVALID_REQUEST"
+	ErrSubscriptionLimitExceeded  ErrorCode = "SUBSCRIPTION_LIMIT_EXCEEDED"
+	ErrConnectionFailed           ErrorCode = "CONNECTION_FAILED"
+	ErrMaxConnectionLimitExceeded ErrorCode = "MAX_CONNECTION_LIMIT_EXCEEDED"
+)
+
+// APIError is returned for every non-200 response of the betting, account
+// and identity endpoints. Code, Details and RequestUUID are only set when the
+// response carried an API exception.
+type APIError struct {
+	Method      string
+	StatusCode  int
+	Status      string
+	Exception   string
+	Code        ErrorCode
+	Details     string
+	RequestUUID string
+	FaultCode   string
+	FaultString string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Method + ": " + e.Status
+	if e.Code != "" {
+		msg += " " + string(e.Code)
+	} else if e.FaultString != "" {
+		msg += " " + e.FaultString
+	}
+	if e.Details != "" {
+		msg += " (" + e.Details + ")"
+	}
+	return msg
+}
+
+// matches an ErrorCode target against the exception code
+func (e *APIError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && e.Code != "" && e.Code == code
+}
+
+// Reports whether the same request may succeed if sent again later
+func (e *APIError) Retryable() bool {
+	switch e.Code {
+	case ErrTooManyRequests, ErrServiceBusy, ErrTimeoutError,
+		ErrUnexpectedError:
+		return true
+	case "":
+		return e.StatusCode >= 500 || e.StatusCode == 429
+	}
+	return false
+}
+
+// Reports whether the session token or application key was rejected
+func (e *APIError) Auth() bool {
+	switch e.Code {
+	case ErrInvalidSessionInformation, ErrNoSession, ErrNoAppKey,
+		ErrInvalidAppKey, ErrAccessDenied, ErrSubscriptionExpired,
+		ErrInvalidSubscriptionToken, ErrUnauthorized:
+		return true
+	case "":
+		return e.StatusCode == 401 || e.StatusCode == 403
+	}
+	return false
+}
+
+// Reports whether the request itself was wrong and must be changed before
+// sending it again
+func (e *APIError) Client() bool {
+	if e.Retryable() || e.Auth() {
+		return false
+	}
+	if e.Code != "" {
+		return true
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// Reports whether err is a retryable APIError
+func IsRetryable(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.Retryable()
+}
+
+// Reports whether err is an authentication related APIError
+func IsAuthError(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.Auth()
+}
+
+// Reports whether err is an APIError caused by invalid request parameters
+func IsClientError(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.Client()
+}
+
+// builds APIError from an unsuccessful response, body is parsed for betting
+// and account exceptions if it is json
+func newAPIError(method string, statusCode int, status string,
+	body []byte) *APIError {
+	e := &APIError{
+		Method:     method,
+		StatusCode: statusCode,
+		Status:     status,
+	}
+
+	var fault struct {
+		Detail      map[string]json.RawMessage
+		FaultCode   string
+		FaultString string
+	}
+	if err := json.Unmarshal(body, &fault); err != nil {
+		return e
+	}
+	e.FaultCode = fault.FaultCode
+	e.FaultString = fault.FaultString
+
+	for name, raw := range fault.Detail {
+		var exception struct {
+			ErrorCode    string
+			ErrorDetails string
+			RequestUUID  string
+		}
+		if err := json.Unmarshal(raw, &exception); err != nil {
+			continue
+		}
+		e.Exception = name
+		e.Code = ErrorCode(exception.ErrorCode)
+		e.Details = exception.ErrorDetails
+		e.RequestUUID = exception.RequestUUID
+		break
+	}
+
+	return e
+}
+
+// LoginStatus is the reason identity rejected a login, the error of
+// interactive login and the loginStatus of cert login. Statuses are errors
+// themselves so they can be matched with errors.Is:
+//
+//	if errors.Is(err, betfair.LoginStrongAuthCodeRequired) { ... }
+type LoginStatus string
+
+func (s LoginStatus) Error() string {
+	return string(s)
+}
+
+// Login statuses other than SUCCESS
+const (
+	LoginInvalidUsernameOrPassword            LoginStatus = "INVALID_USERNAME_OR_PASSWORD"
+	LoginStrongAuthCodeRequired               LoginStatus = "STRONG_AUTH_CODE_REQUIRED"
+	LoginAccountNowLocked                     LoginStatus = "ACCOUNT_NOW_LOCKED"
+	LoginAccountAlreadyLocked                 LoginStatus = "ACCOUNT_ALREADY_LOCKED"
+	LoginPendingAuth                          LoginStatus = "PENDING_AUTH"
+	LoginTelbetTermsConditionsNA              LoginStatus = "TELBET_TERMS_CONDITIONS_NA"
+	LoginDuplicateCards                       LoginStatus = "DUPLICATE_CARDS"
+	LoginSecurityQuestionWrong3X              LoginStatus = "SECURITY_QUESTION_WRONG_3X"
+	LoginKYCSuspend                           LoginStatus = "KYC_SUSPEND"
+	LoginSuspended                            LoginStatus = "SUSPENDED"
+	LoginClosed                               LoginStatus = "CLOSED"
+	LoginSelfExcluded                         LoginStatus = "SELF_EXCLUDED"
+	LoginInvalidConnectivityToRegulatorDK     LoginStatus = "INVALID_CONNECTIVITY_TO_REGULATOR_DK"
+	LoginNotAuthorizedByRegulatorDK           LoginStatus = "NOT_AUTHORIZED_BY_REGULATOR_DK"
+	LoginInvalidConnectivityToRegulatorIT     LoginStatus = "INVALID_CONNECTIVITY_TO_REGULATOR_IT"
+	LoginNotAuthorizedByRegulatorIT           LoginStatus = "NOT_AUTHORIZED_BY_REGULATOR_IT"
+	LoginSecurityRestrictedLocation           LoginStatus = "SECURITY_RESTRICTED_LOCATION"
+	LoginBettingRestrictedLocation            LoginStatus = "BETTING_RESTRICTED_LOCATION"
+	LoginTradingMaster                        LoginStatus = "TRADING_MASTER"
+	LoginTradingMasterSuspended               LoginStatus = "TRADING_MASTER_SUSPENDED"
+	LoginAgentClientMaster                    LoginStatus = "AGENT_CLIENT_MASTER"
+	LoginAgentClientMasterSuspended           LoginStatus = "AGENT_CLIENT_MASTER_SUSPENDED"
+	LoginDanishAuthorizationRequired          LoginStatus = "DANISH_AUTHORIZATION_REQUIRED"
+	LoginSpainMigrationRequired               LoginStatus = "SPAIN_MIGRATION_REQUIRED"
+	LoginDenmarkMigrationRequired             LoginStatus = "DENMARK_MIGRATION_REQUIRED"
+	LoginSpanishTermsAcceptanceRequired       LoginStatus = "SPANISH_TERMS_ACCEPTANCE_REQUIRED"
+	LoginItalianContractAcceptanceRequired    LoginStatus = "ITALIAN_CONTRACT_ACCEPTANCE_REQUIRED"
+	LoginCertAuthRequired                     LoginStatus = "CERT_AUTH_REQUIRED"
+	LoginChangePasswordRequired               LoginStatus = "CHANGE_PASSWORD_REQUIRED"
+	LoginPersonalMessageRequired              LoginStatus = "PERSONAL_MESSAGE_REQUIRED"
+	LoginInternationalTermsAcceptanceRequired LoginStatus = "INTERNATIONAL_TERMS_ACCEPTANCE_REQUIRED"
+	LoginEmailLoginNotAllowed                 LoginStatus = "EMAIL_LOGIN_NOT_ALLOWED"
+	LoginMultipleUsersWithSameCredential      LoginStatus = "MULTIPLE_USERS_WITH_SAME_CREDENTIAL"
+	LoginAccountPendingPasswordChange         LoginStatus = "ACCOUNT_PENDING_PASSWORD_CHANGE"
+	LoginTemporaryBanTooManyRequests          LoginStatus = "TEMPORARY_BAN_TOO_MANY_REQUESTS"
+	LoginItalianProfilingAcceptanceRequired   LoginStatus = "ITALIAN_PROFILING_ACCEPTANCE_REQUIRED"
+	LoginAuthorizedOnlyForDomainRO            LoginStatus = "AUTHORIZED_ONLY_FOR_DOMAIN_RO"
+	LoginAuthorizedOnlyForDomainSE            LoginStatus = "AUTHORIZED_ONLY_FOR_DOMAIN_SE"
+)
+
+// LoginError is returned when identity answers a login without a token.
+// Status is the overall status of interactive login (FAIL, LIMITED_ACCESS,
+// LOGIN_RESTRICTED) and the loginStatus of cert login, Code is the reason.
+type LoginError struct {
+	Method string
+	Status string
+	Code   LoginStatus
+}
+
+func (e *LoginError) Error() string {
+	msg := e.Method + ": " + e.Status
+	if e.Code != "" && string(e.Code) != e.Status {
+		msg += " " + string(e.Code)
+	}
+	if e.Code == LoginStrongAuthCodeRequired {
+		msg += " (two-step authentication code missing or wrong)"
+	}
+	return msg
+}
+
+// matches a LoginStatus target against the login status code
+func (e *LoginError) Is(target error) bool {
+	code, ok := target.(LoginStatus)
+	return ok && e.Code != "" && e.Code == code
+}
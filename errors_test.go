@@ -0,0 +1,58 @@
+package betfair
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_newAPIError(t *testing.T) {
+	body := []byte(`{"detail":{"APINGException":{"requestUUID":"prdang-123",` +
+		`"errorCode":"INVALID_SESSION_INFORMATION","errorDetails":"bad token"}},` +
+		`"faultcode":"Client","faultstring":"ANGX-0003"}`)
+
+	var err error = newAPIError("listEvents", 400, "400 Bad Request", body)
+	if !errors.Is(err, ErrInvalidSessionInformation) {
+		t.Error("error code not matched")
+	}
+	if errors.Is(err, ErrTooMuchData) {
+		t.Error("wrong error code matched")
+	}
+
+	var e *APIError
+	if !errors.As(err, &e) {
+		t.Fatal("not an APIError")
+	}
+	if e.Exception != "APINGException" || e.RequestUUID != "prdang-123" ||
+		e.FaultString != "ANGX-0003" || e.Details != "bad token" {
+		t.Error("exception not parsed", e)
+	}
+	if !IsAuthError(err) || IsRetryable(err) || IsClientError(err) {
+		t.Error("wrong classification")
+	}
+}
+
+func Test_newAPIErrorAccount(t *testing.T) {
+	body := []byte(`{"detail":{"AccountAPINGException":{"requestUUID":"x",` +
+		`"errorCode":"SERVICE_BUSY","errorDetails":""}},` +
+		`"faultcode":"Client","faultstring":"AANGX-0006"}`)
+
+	err := newAPIError("getAccountFunds", 400, "400 Bad Request", body)
+	if err.Exception != "AccountAPINGException" || err.Code != ErrServiceBusy {
+		t.Error("account exception not parsed", err)
+	}
+	if !err.Retryable() {
+		t.Error("busy service not retryable")
+	}
+
+	err = newAPIError("listEvents", 503, "503 Service Unavailable",
+		[]byte("<html></html>"))
+	if err.Code != "" || !err.Retryable() {
+		t.Error("unavailable service not retryable")
+	}
+
+	err = newAPIError("listEvents", 400, "400 Bad Request", []byte(
+		`{"detail":{"APINGException":{"errorCode":"TOO_MUCH_DATA"}}}`))
+	if !err.Client() {
+		t.Error("too much data not a client error")
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func certLogin() *betfair.Session {
-	c, err := betfair.NewCredentials("<USERNAME>", "<PASS>", "UK",
+	c, err := betfair.NewCertCredentials("<USERNAME>", "<PASS>", "UK",
 		"client-2048.crt",
 		"client-2048.key")
 	if err != nil {
@@ -30,7 +30,7 @@ func certLogin() *betfair.Session {
 }
 
 func restLogin() *betfair.Session {
-	c, err := betfair.NewCredentials("<USERNAME>", "<PASS>", "UK",
+	c, err := betfair.NewInteractiveCredentials("<USERNAME>", "<PASS>", "UK",
 		"<APPKEY>")
 	if err != nil {
 		fmt.Println(err)
@@ -72,10 +72,9 @@ func main() {
 	acc, _ := session.GetAccountDetails()
 	fmt.Println(acc)
 
-	query := &betfair.Query{
-		MaxResults: 1000,
-		MarketIds:  []string{"1.116734361"},
-		Locale:     "en",
+	query := &betfair.ListMarketBookRequest{
+		MarketIds: []string{"1.116734361"},
+		Locale:    "en",
 		PriceProjection: &betfair.PriceProjection{
 			PriceData: []string{"EX_ALL_OFFERS"},
 		},
@@ -0,0 +1,7 @@
+module betfair
+
+go 1.21
+
+require software.sslmate.com/src/go-pkcs12 v0.7.3
+
+require golang.org/x/crypto v0.11.0 // indirect
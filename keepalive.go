@@ -0,0 +1,171 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Interval used by StartKeepAlive when none is given. Sessions expire after
+// 20 minutes of inactivity on the Italian and Spanish exchanges and after
+// hours elsewhere, so this suits every jurisdiction.
+const DefaultKeepAliveInterval = 15 * time.Minute
+
+// background keep-alive goroutine state
+type keepAliveLoop struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Extends the session token lifetime
+func (s *Session) KeepAlive() error {
+	return s.KeepAliveContext(context.Background())
+}
+
+// Like KeepAlive, ctx controls the request
+func (s *Session) KeepAliveContext(ctx context.Context) error {
+	token, err := identityRequest(ctx, s, "keepAlive")
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		s.setToken(token)
+	}
+	return nil
+}
+
+// Invalidates the session token and stops the background keep-alive
+func (s *Session) Logout() error {
+	return s.LogoutContext(context.Background())
+}
+
+// Like Logout, ctx controls the request
+func (s *Session) LogoutContext(ctx context.Context) error {
+	s.StopKeepAlive()
+	if _, err := identityRequest(ctx, s, "logout"); err != nil {
+		return err
+	}
+	s.setToken("")
+	return nil
+}
+
+// Starts a goroutine calling KeepAlive every interval until StopKeepAlive or
+// Logout is called, DefaultKeepAliveInterval is used if interval is not
+// positive. A session expired in the meantime is logged in again.
+func (s *Session) StartKeepAlive(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultKeepAliveInterval
+	}
+
+	loop := &keepAliveLoop{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	// swapped in one step, concurrent callers each stop the loop they replaced
+	s.mu.Lock()
+	old := s.keepAlive
+	s.keepAlive = loop
+	s.mu.Unlock()
+	old.halt()
+
+	go func() {
+		defer close(loop.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-loop.stop:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			s.keepAliveOnce(ctx)
+			cancel()
+		}
+	}()
+}
+
+// Stops the goroutine started by StartKeepAlive and waits for it to exit
+func (s *Session) StopKeepAlive() {
+	s.mu.Lock()
+	loop := s.keepAlive
+	s.keepAlive = nil
+	s.mu.Unlock()
+
+	loop.halt()
+}
+
+// stops the goroutine of l and waits for it to exit, l may be nil
+func (l *keepAliveLoop) halt() {
+	if l != nil {
+		close(l.stop)
+		<-l.done
+	}
+}
+
+// one iteration of the keep-alive goroutine
+func (s *Session) keepAliveOnce(ctx context.Context) {
+	token := s.sessionToken()
+	err := s.KeepAliveContext(ctx)
+	if err == nil {
+		s.log().Debug("session kept alive")
+		return
+	}
+
+	if !errors.Is(err, ErrNoSession) &&
+		!errors.Is(err, ErrInvalidSessionInformation) {
+		s.log().Warn("keep alive failed", "err", err)
+		return
+	}
+
+	s.log().Info("session expired, logging in again")
+	if err := s.relogin(ctx, token); err != nil {
+		s.log().Error("re-login failed", "err", err)
+	}
+}
+
+// logs in again unless another caller already replaced the stale token
+func (s *Session) relogin(ctx context.Context, stale string) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+
+	if s.sessionToken() != stale {
+		return nil
+	}
+	return s.login(ctx)
+}
+
+// calls keepAlive or logout, failures reported in the body are returned as
+// APIError with the identity error as code
+func identityRequest(ctx context.Context, s *Session, endpoint string) (
+	string, error) {
+	resp, err := doRequest(ctx, s, endpoint, "", strings.NewReader(""))
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Token   string
+		Product string
+		Status  string
+		Error   string
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", fmt.Errorf("%s: decoding response: %w", endpoint, err)
+	}
+
+	if result.Status != "SUCCESS" {
+		return "", &APIError{
+			Method:     endpoint,
+			StatusCode: 200,
+			Status:     result.Status,
+			Code:       ErrorCode(result.Error),
+		}
+	}
+	return result.Token, nil
+}
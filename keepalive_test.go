@@ -0,0 +1,198 @@
+package betfair
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// identity stand-in counting calls, keepAlive answers with the result of
+// keepAlive and betting calls with the result of betting
+type identityTestServer struct {
+	*stubServer
+	keepAlives, logouts, bettingCalls atomic.Int32
+
+	keepAlive func(token string) string
+	betting   func(token string) (int, string)
+}
+
+func newIdentityTestServer(t *testing.T, srv *identityTestServer) *Session {
+	srv.stubServer = newStubServer(t)
+	srv.mux.HandleFunc("/api/keepAlive", func(w http.ResponseWriter,
+		r *http.Request) {
+		srv.keepAlives.Add(1)
+		token := r.Header.Get("X-Authentication")
+		if srv.keepAlive != nil {
+			fmt.Fprint(w, srv.keepAlive(token))
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q,"status":"SUCCESS","error":""}`, token)
+	})
+	srv.mux.HandleFunc("/api/logout", func(w http.ResponseWriter,
+		r *http.Request) {
+		srv.logouts.Add(1)
+		if r.Header.Get("X-Authentication") == "" {
+			t.Error("logout without session token")
+		}
+		fmt.Fprint(w, `{"token":"","status":"SUCCESS","error":""}`)
+	})
+	srv.mux.HandleFunc("/betting/listEvents/", func(w http.ResponseWriter,
+		r *http.Request) {
+		srv.bettingCalls.Add(1)
+		code, body := srv.betting(r.Header.Get("X-Authentication"))
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	})
+	return srv.session(t)
+}
+
+// waits until cond holds or fails the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func Test_StartKeepAlive(t *testing.T) {
+	srv := &identityTestServer{}
+	s := newIdentityTestServer(t, srv)
+
+	// concurrent starts leave a single loop StopKeepAlive can end
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			s.StartKeepAlive(5 * time.Millisecond)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	waitFor(t, "keep alive", func() bool { return srv.keepAlives.Load() >= 3 })
+	s.StopKeepAlive()
+	n := srv.keepAlives.Load()
+	time.Sleep(30 * time.Millisecond)
+	if srv.keepAlives.Load() != n {
+		t.Error("keep alive goroutine still running after StopKeepAlive")
+	}
+	if s.sessionToken() != "token-1" {
+		t.Error("session token changed", s.sessionToken())
+	}
+
+	// stopping twice is harmless
+	s.StopKeepAlive()
+}
+
+func Test_KeepAliveRelogin(t *testing.T) {
+	srv := &identityTestServer{
+		keepAlive: func(token string) string {
+			if token == "token-1" {
+				return `{"token":"","status":"FAIL","error":"NO_SESSION"}`
+			}
+			return fmt.Sprintf(`{"token":%q,"status":"SUCCESS"}`, token)
+		},
+	}
+	s := newIdentityTestServer(t, srv)
+
+	if err := s.KeepAlive(); !errors.Is(err, ErrNoSession) {
+		t.Error("expired session not reported", err)
+	}
+
+	s.StartKeepAlive(5 * time.Millisecond)
+	defer s.StopKeepAlive()
+	waitFor(t, "re-login", func() bool { return s.sessionToken() == "token-2" })
+	if srv.logins.Load() != 2 {
+		t.Error("unexpected logins", srv.logins.Load())
+	}
+}
+
+func Test_Logout(t *testing.T) {
+	srv := &identityTestServer{}
+	s := newIdentityTestServer(t, srv)
+
+	s.StartKeepAlive(5 * time.Millisecond)
+	waitFor(t, "keep alive", func() bool { return srv.keepAlives.Load() > 0 })
+
+	if err := s.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	n := srv.keepAlives.Load()
+	time.Sleep(30 * time.Millisecond)
+	if srv.keepAlives.Load() != n {
+		t.Error("keep alive goroutine still running after Logout")
+	}
+	if srv.logouts.Load() != 1 || s.sessionToken() != "" {
+		t.Error("session not logged out", srv.logouts.Load(), s.sessionToken())
+	}
+}
+
+func Test_doRequestRelogin(t *testing.T) {
+	expired := func(code ErrorCode) (int, string) {
+		return 400, fmt.Sprintf(`{"detail":{"APINGException":`+
+			`{"errorCode":%q}}}`, code)
+	}
+
+	for _, code := range []ErrorCode{ErrInvalidSessionInformation,
+		ErrNoSession} {
+		srv := &identityTestServer{
+			betting: func(token string) (int, string) {
+				if token == "token-1" {
+					return expired(code)
+				}
+				return 200, `[{"event":{"id":"1","name":"A v B"}}]`
+			},
+		}
+		s := newIdentityTestServer(t, srv)
+
+		events, err := s.ListEvents(&ListEventsRequest{})
+		if err != nil {
+			t.Fatal(code, err)
+		}
+		if len(events) != 1 || srv.logins.Load() != 2 ||
+			srv.bettingCalls.Load() != 2 {
+			t.Error(code, "request not retried after re-login", events)
+		}
+	}
+
+	// the retry is made only once
+	srv := &identityTestServer{
+		betting: func(token string) (int, string) {
+			return expired(ErrNoSession)
+		},
+	}
+	s := newIdentityTestServer(t, srv)
+	if _, err := s.ListEvents(&ListEventsRequest{}); !errors.Is(err,
+		ErrNoSession) {
+		t.Error("expired session not reported", err)
+	}
+	if srv.logins.Load() != 2 || srv.bettingCalls.Load() != 2 {
+		t.Error("unexpected retries", srv.logins.Load(),
+			srv.bettingCalls.Load())
+	}
+
+	// other errors are not retried
+	srv = &identityTestServer{
+		betting: func(token string) (int, string) {
+			return expired(ErrTooMuchData)
+		},
+	}
+	s = newIdentityTestServer(t, srv)
+	if _, err := s.ListEvents(&ListEventsRequest{}); err == nil {
+		t.Error("error not returned")
+	}
+	if srv.logins.Load() != 1 || srv.bettingCalls.Load() != 1 {
+		t.Error("request retried", srv.logins.Load(), srv.bettingCalls.Load())
+	}
+}
@@ -0,0 +1,91 @@
+package betfair
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Logger is the leveled logger used by Session. Arguments after msg are
+// alternating keys and values as in log/slog, so *slog.Logger satisfies it.
+//
+// Request and response payloads are only logged at debug level.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Returns a Logger writing to l, slog.Default() is used if l is nil
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return l.With("pkg", PKG_NAME)
+}
+
+// Returns a Logger printing records of at least level min to l
+func NewStdLogger(l *log.Logger, min slog.Level) Logger {
+	return &stdLogger{l: l, min: min}
+}
+
+// Returns a Logger discarding every record
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+// default logger of sessions, out is os.Stderr unless NewSession got a writer
+func newDefaultLogger(out io.Writer) Logger {
+	return NewStdLogger(log.New(out, PKG_NAME+" ", log.LstdFlags),
+		slog.LevelInfo)
+}
+
+type stdLogger struct {
+	l   *log.Logger
+	min slog.Level
+}
+
+func (s *stdLogger) log(level slog.Level, msg string, args []interface{}) {
+	if level < s.min {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " !BADKEY=%v", args[i])
+		}
+	}
+	s.l.Print(b.String())
+}
+
+func (s *stdLogger) Debug(msg string, args ...interface{}) {
+	s.log(slog.LevelDebug, msg, args)
+}
+
+func (s *stdLogger) Info(msg string, args ...interface{}) {
+	s.log(slog.LevelInfo, msg, args)
+}
+
+func (s *stdLogger) Warn(msg string, args ...interface{}) {
+	s.log(slog.LevelWarn, msg, args)
+}
+
+func (s *stdLogger) Error(msg string, args ...interface{}) {
+	s.log(slog.LevelError, msg, args)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
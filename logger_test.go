@@ -0,0 +1,105 @@
+package betfair
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_StdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(log.New(&buf, "", 0), slog.LevelInfo)
+
+	l.Debug("request", "payload", "secret")
+	l.Info("connected", "addr", "host:443", "odd")
+	l.Warn("retry")
+	l.Error("failed", "err", "boom")
+
+	want := "INFO connected addr=host:443 !BADKEY=odd\n" +
+		"WARN retry\n" +
+		"ERROR failed err=boom\n"
+	if buf.String() != want {
+		t.Errorf("unexpected output %q, want %q", buf.String(), want)
+	}
+}
+
+func Test_SlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf,
+		&slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debug("request", "payload", "secret")
+	l.Info("connected", "addr", "host:443")
+
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Error("debug record not suppressed", out)
+	}
+	if !strings.Contains(out, "msg=connected") ||
+		!strings.Contains(out, "addr=host:443") ||
+		!strings.Contains(out, "pkg="+PKG_NAME) {
+		t.Error("info record not written", out)
+	}
+
+	if NewSlogLogger(nil) == nil {
+		t.Error("no logger for nil slog logger")
+	}
+}
+
+func Test_DefaultLoggerHidesPayloads(t *testing.T) {
+	srv := newStubServer(t)
+	srv.handleMethods(t, "/betting/", testMethods{
+		"listEvents": func(p map[string]interface{}) string {
+			return `[{"event":{"id":"1","name":"response-body"}}]`
+		},
+	})
+	s := srv.session(t)
+	r := &ListEventsRequest{Filter: MarketFilter{TextQuery: "request-payload"}}
+
+	// debug records carrying payloads are dropped at the default level
+	var buf bytes.Buffer
+	s.SetLogger(newDefaultLogger(&buf))
+	if _, err := s.ListEvents(r); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "request-payload") ||
+		strings.Contains(buf.String(), "response-body") {
+		t.Error("payload logged at info level", buf.String())
+	}
+
+	buf.Reset()
+	s.SetLogger(NewStdLogger(log.New(&buf, "", 0), slog.LevelDebug))
+	if _, err := s.ListEvents(r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "request-payload") ||
+		!strings.Contains(buf.String(), "response-body") {
+		t.Error("payload not logged at debug level", buf.String())
+	}
+}
+
+func Test_SetLoggerConcurrent(t *testing.T) {
+	srv := &identityTestServer{}
+	s := newIdentityTestServer(t, srv)
+
+	// replacing the logger while keep-alive logs must not race
+	s.StartKeepAlive(time.Millisecond)
+	defer s.StopKeepAlive()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.SetLogger(NewNopLogger())
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+	waitFor(t, "keep alive", func() bool { return srv.keepAlives.Load() > 0 })
+}
@@ -0,0 +1,361 @@
+package betfair
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MarketSnapshot is a cached stream market as a MarketBook, so consumers of
+// ListMarketBook results can be fed from the stream, plus its last market
+// definition and publish time. StreamStatus is the Status of the latest
+// change message, StreamStatusStale while the stream reports stale data.
+type MarketSnapshot struct {
+	MarketBook
+	Definition   *MarketDefinition
+	PublishTime  time.Time
+	StreamStatus int
+}
+
+// MarketCache rebuilds market state from the deltas of market change
+// messages. It is safe for concurrent use.
+type MarketCache struct {
+	mu      sync.RWMutex
+	markets map[string]*marketState
+	status  int // of the latest change message
+}
+
+// cached state of a market
+type marketState struct {
+	def     *MarketDefinition
+	tv      float64
+	pt      time.Time
+	runners map[runnerKey]*runnerState
+}
+
+// runners are identified by selection id and handicap
+type runnerKey struct {
+	id uint32
+	hc float64
+}
+
+// cached ladders and prices of a runner, full ladders map price to size and
+// best offer ladders map level to price and size
+type runnerState struct {
+	ltp, tv, spn, spf        float64
+	atb, atl, spb, spl       map[float64]float64
+	trd                      map[float64]float64
+	batb, batl, bdatb, bdatl map[int]PriceVol
+}
+
+// Returns an empty market cache
+func NewMarketCache() *MarketCache {
+	return &MarketCache{markets: make(map[string]*marketState)}
+}
+
+// Applies the changes of msg, returns ids of the changed markets
+func (c *MarketCache) Apply(msg *MarketChangeMessage) []string {
+	if msg == nil {
+		return nil
+	}
+	pt := time.UnixMilli(msg.Pt)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.status = msg.Status
+	if len(msg.Mc) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(msg.Mc))
+	for i := range msg.Mc {
+		mc := &msg.Mc[i]
+		m := c.markets[mc.Id]
+		if m == nil || mc.Img {
+			next := &marketState{runners: make(map[runnerKey]*runnerState)}
+			if m != nil {
+				next.def = m.def
+			}
+			m = next
+			c.markets[mc.Id] = m
+		}
+		m.apply(mc)
+		m.pt = pt
+		ids = append(ids, mc.Id)
+	}
+	return ids
+}
+
+// Returns the cached market
+func (c *MarketCache) Snapshot(marketId string) (MarketSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return MarketSnapshot{}, false
+	}
+	return m.snapshot(marketId, c.status), true
+}
+
+// Returns every cached market ordered by market id
+func (c *MarketCache) Snapshots() []MarketSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.markets))
+	for id := range c.markets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	snapshots := make([]MarketSnapshot, len(ids))
+	for i, id := range ids {
+		snapshots[i] = c.markets[id].snapshot(id, c.status)
+	}
+	return snapshots
+}
+
+// Returns market books of every cached market ordered by market id, as
+// ListMarketBook would
+func (c *MarketCache) MarketBooks() []MarketBook {
+	snapshots := c.Snapshots()
+	books := make([]MarketBook, len(snapshots))
+	for i := range snapshots {
+		books[i] = snapshots[i].MarketBook
+	}
+	return books
+}
+
+// Drops the market, e.g. once it is closed
+func (c *MarketCache) Remove(marketId string) {
+	c.mu.Lock()
+	delete(c.markets, marketId)
+	c.mu.Unlock()
+}
+
+func (m *marketState) apply(mc *MarketChange) {
+	if mc.MarketDefinition != nil {
+		m.def = mc.MarketDefinition
+	}
+	if mc.Tv != 0 {
+		m.tv = mc.Tv
+	}
+
+	for i := range mc.Rc {
+		rc := &mc.Rc[i]
+		key := runnerKey{rc.Id, rc.Hc}
+		r := m.runners[key]
+		if r == nil {
+			r = &runnerState{}
+			m.runners[key] = r
+		}
+		r.apply(rc)
+	}
+}
+
+func (r *runnerState) apply(rc *RunnerChange) {
+	if rc.Ltp != 0 {
+		r.ltp = rc.Ltp
+	}
+	if rc.Tv != 0 {
+		r.tv = rc.Tv
+	}
+	if rc.Spn != 0 {
+		r.spn = rc.Spn
+	}
+	if rc.Spf != 0 {
+		r.spf = rc.Spf
+	}
+
+	r.atb = updateLadder(r.atb, rc.Atb)
+	r.atl = updateLadder(r.atl, rc.Atl)
+	r.spb = updateLadder(r.spb, rc.Spb)
+	r.spl = updateLadder(r.spl, rc.Spl)
+	r.trd = updateLadder(r.trd, rc.Trd)
+	r.batb = updateLevels(r.batb, rc.Batb)
+	r.batl = updateLevels(r.batl, rc.Batl)
+	r.bdatb = updateLevels(r.bdatb, rc.Bdatb)
+	r.bdatl = updateLevels(r.bdatl, rc.Bdatl)
+}
+
+// sets sizes of the changed prices, zero size removes a price
+func updateLadder(ladder map[float64]float64,
+	changes []PriceVol) map[float64]float64 {
+	if len(changes) == 0 {
+		return ladder
+	}
+	if ladder == nil {
+		ladder = make(map[float64]float64, len(changes))
+	}
+	for _, pv := range changes {
+		if pv[1] == 0 {
+			delete(ladder, pv[0])
+		} else {
+			ladder[pv[0]] = pv[1]
+		}
+	}
+	return ladder
+}
+
+// sets price and size of the changed levels, zero size removes a level
+func updateLevels(levels map[int]PriceVol,
+	changes []LevelPriceVol) map[int]PriceVol {
+	if len(changes) == 0 {
+		return levels
+	}
+	if levels == nil {
+		levels = make(map[int]PriceVol, len(changes))
+	}
+	for _, lpv := range changes {
+		level := int(lpv[0])
+		if lpv[2] == 0 {
+			delete(levels, level)
+		} else {
+			levels[level] = PriceVol{lpv[1], lpv[2]}
+		}
+	}
+	return levels
+}
+
+// returns ladder as price sizes, best (highest) price first if desc
+func ladderPrices(ladder map[float64]float64, desc bool) []PriceSize {
+	if len(ladder) == 0 {
+		return nil
+	}
+	prices := make([]PriceSize, 0, len(ladder))
+	for price, size := range ladder {
+		prices = append(prices, PriceSize{Price: price, Size: size})
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if desc {
+			return prices[i].Price > prices[j].Price
+		}
+		return prices[i].Price < prices[j].Price
+	})
+	return prices
+}
+
+// returns levels as price sizes, best level first
+func levelPrices(levels map[int]PriceVol) []PriceSize {
+	if len(levels) == 0 {
+		return nil
+	}
+	keys := make([]int, 0, len(levels))
+	for level := range levels {
+		keys = append(keys, level)
+	}
+	sort.Ints(keys)
+
+	prices := make([]PriceSize, len(keys))
+	for i, level := range keys {
+		pv := levels[level]
+		prices[i] = PriceSize{Price: pv[0], Size: pv[1]}
+	}
+	return prices
+}
+
+// returns the first non-empty ladder
+func firstPrices(ladders ...[]PriceSize) []PriceSize {
+	for _, l := range ladders {
+		if len(l) > 0 {
+			return l
+		}
+	}
+	return nil
+}
+
+// returns the market with its own copy of every slice, so callers can not
+// change the cached state
+func (m *marketState) snapshot(marketId string, status int) MarketSnapshot {
+	snap := MarketSnapshot{
+		MarketBook: MarketBook{
+			MarketId:     marketId,
+			TotalMatched: m.tv,
+		},
+		PublishTime:  m.pt,
+		StreamStatus: status,
+	}
+
+	// runners of the definition come first in their sort priority order
+	var keys []runnerKey
+	seen := make(map[runnerKey]bool)
+	defs := make(map[runnerKey]*RunnerDefinition)
+	if d := m.def; d != nil {
+		def := *d
+		def.Regulators = append([]string(nil), d.Regulators...)
+		def.Runners = append([]RunnerDefinition(nil), d.Runners...)
+		snap.Definition = &def
+		snap.Status = d.Status
+		snap.BetDelay = d.BetDelay
+		snap.BspReconciled = d.BspReconciled
+		snap.Complete = d.Complete
+		snap.Inplay = d.InPlay
+		snap.NumberOfWinners = d.NumberOfWinners
+		snap.NumberOfRunners = len(d.Runners)
+		snap.NumberOfActiveRunners = d.NumberOfActiveRunners
+		snap.CrossMatching = d.CrossMatching
+		snap.RunnersVoidable = d.RunnersVoidable
+		snap.Version = uint32(d.Version)
+
+		runners := make([]RunnerDefinition, len(d.Runners))
+		copy(runners, d.Runners)
+		sort.SliceStable(runners, func(i, j int) bool {
+			return runners[i].SortPriority < runners[j].SortPriority
+		})
+		for i := range runners {
+			key := runnerKey{runners[i].Id, runners[i].Hc}
+			defs[key] = &runners[i]
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+
+	var extra []runnerKey
+	for key := range m.runners {
+		if !seen[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		if extra[i].id != extra[j].id {
+			return extra[i].id < extra[j].id
+		}
+		return extra[i].hc < extra[j].hc
+	})
+	keys = append(keys, extra...)
+
+	snap.Runners = make([]Runner, len(keys))
+	for i, key := range keys {
+		runner := &snap.Runners[i]
+		runner.SelectionId = key.id
+		runner.Handicap = key.hc
+
+		if d := defs[key]; d != nil {
+			runner.Status = d.Status
+			runner.AdjustmentFactor = d.AdjustmentFactor
+			runner.Sp.ActualSP = d.Bsp
+			if d.RemovalDate != "" {
+				runner.RemovalDate, _ = time.Parse(time.RFC3339, d.RemovalDate)
+			}
+		}
+
+		r := m.runners[key]
+		if r == nil {
+			continue
+		}
+		runner.LastPriceTraded = r.ltp
+		runner.TotalMatched = r.tv
+		runner.Sp.NearPrice = r.spn
+		runner.Sp.FarPrice = r.spf
+		runner.Sp.BackStakeTaken = ladderPrices(r.spb, true)
+		runner.Sp.LayLiabilityTaken = ladderPrices(r.spl, false)
+		runner.Ex.AvailableToBack = firstPrices(ladderPrices(r.atb, true),
+			levelPrices(r.batb), levelPrices(r.bdatb))
+		runner.Ex.AvailableToLay = firstPrices(ladderPrices(r.atl, false),
+			levelPrices(r.batl), levelPrices(r.bdatl))
+		runner.Ex.TradedVolume = ladderPrices(r.trd, false)
+	}
+	return snap
+}
@@ -0,0 +1,120 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// decodes a market change message
+func testMcm(t *testing.T, s string) *MarketChangeMessage {
+	var msg MarketChangeMessage
+	if err := json.Unmarshal([]byte(s), &msg); err != nil {
+		t.Fatal(err)
+	}
+	return &msg
+}
+
+func Test_MarketCache(t *testing.T) {
+	c := NewMarketCache()
+	c.Apply(testMcm(t, `{"op":"mcm","pt":1500000000000,"ct":"SUB_IMAGE",`+
+		`"mc":[{"id":"1.2","img":true,"tv":100,"marketDefinition":{`+
+		`"status":"OPEN","inPlay":false,"betDelay":0,"numberOfWinners":1,`+
+		`"numberOfActiveRunners":2,"version":7,"runners":[`+
+		`{"id":11,"sortPriority":2,"status":"ACTIVE"},`+
+		`{"id":10,"sortPriority":1,"status":"ACTIVE","adjustmentFactor":50}]},`+
+		`"rc":[{"id":10,"atb":[[2.5,10],[2.4,20]],"atl":[[2.6,5]],`+
+		`"trd":[[2.5,40]],"ltp":2.5,"tv":40},`+
+		`{"id":11,"batb":[[0,1.9,3],[1,1.8,4]]}]}]}`))
+
+	// deltas update and remove price points
+	ids := c.Apply(testMcm(t, `{"op":"mcm","pt":1500000001000,`+
+		`"mc":[{"id":"1.2","tv":110,"rc":[{"id":10,"atb":[[2.4,0],[2.52,7]],`+
+		`"trd":[[2.5,50]],"tv":50},{"id":11,"batb":[[0,1.95,2]]}]}]}`))
+	if len(ids) != 1 || ids[0] != "1.2" {
+		t.Error("changed markets not returned", ids)
+	}
+
+	snap, ok := c.Snapshot("1.2")
+	if !ok {
+		t.Fatal("market not cached")
+	}
+	if snap.Status != "OPEN" || snap.Version != 7 || snap.NumberOfRunners != 2 ||
+		snap.TotalMatched != 110 || snap.Definition == nil ||
+		snap.PublishTime.UnixMilli() != 1500000001000 {
+		t.Error("market state wrong", snap.MarketBook)
+	}
+
+	if len(snap.Runners) != 2 || snap.Runners[0].SelectionId != 10 {
+		t.Fatal("runners not in sort priority order", snap.Runners)
+	}
+	r := snap.Runners[0]
+	back := r.Ex.AvailableToBack
+	if len(back) != 2 || back[0] != (PriceSize{2.52, 7}) ||
+		back[1] != (PriceSize{2.5, 10}) {
+		t.Error("back ladder wrong", back)
+	}
+	if r.Ex.AvailableToLay[0] != (PriceSize{2.6, 5}) ||
+		r.Ex.TradedVolume[0] != (PriceSize{2.5, 50}) ||
+		r.LastPriceTraded != 2.5 || r.TotalMatched != 50 ||
+		r.AdjustmentFactor != 50 {
+		t.Error("runner state wrong", r)
+	}
+
+	best := snap.Runners[1].Ex.AvailableToBack
+	if len(best) != 2 || best[0] != (PriceSize{1.95, 2}) {
+		t.Error("best offers wrong", best)
+	}
+
+	// an image replaces the market
+	c.Apply(testMcm(t, `{"op":"mcm","mc":[{"id":"1.2","img":true,`+
+		`"rc":[{"id":10,"atb":[[3,1]]}]}]}`))
+	books := c.MarketBooks()
+	if len(books) != 1 || len(books[0].Runners[0].Ex.AvailableToBack) != 1 ||
+		books[0].Runners[1].Ex.AvailableToBack != nil {
+		t.Error("image not replacing market", books)
+	}
+
+	c.Remove("1.2")
+	if _, ok := c.Snapshot("1.2"); ok {
+		t.Error("market not removed")
+	}
+}
+
+func Test_MarketCacheSnapshotCopy(t *testing.T) {
+	c := NewMarketCache()
+	c.Apply(testMcm(t, `{"op":"mcm","mc":[{"id":"1.2","img":true,`+
+		`"marketDefinition":{"status":"OPEN","regulators":["MR_INT"],`+
+		`"runners":[{"id":10,"sortPriority":1,"status":"ACTIVE"}]}}]}`))
+
+	snap, _ := c.Snapshot("1.2")
+	snap.Definition.Runners[0].Status = "REMOVED"
+	snap.Definition.Regulators[0] = "changed"
+	snap.Definition.Status = "CLOSED"
+
+	snap, _ = c.Snapshot("1.2")
+	d := snap.Definition
+	if d.Status != "OPEN" || d.Regulators[0] != "MR_INT" ||
+		d.Runners[0].Status != "ACTIVE" || snap.Runners[0].Status != "ACTIVE" {
+		t.Error("snapshot changes reached the cache", d)
+	}
+}
+
+func Test_MarketCacheStale(t *testing.T) {
+	c := NewMarketCache()
+	c.Apply(testMcm(t, `{"op":"mcm","mc":[{"id":"1.2","img":true,"tv":5}]}`))
+	if snap, _ := c.Snapshot("1.2"); snap.StreamStatus != 0 {
+		t.Error("fresh market reported stale", snap.StreamStatus)
+	}
+
+	// stale status is sent with heartbeats as well as changes
+	c.Apply(testMcm(t, `{"op":"mcm","ct":"HEARTBEAT","status":503}`))
+	if snap, _ := c.Snapshot("1.2"); snap.StreamStatus != StreamStatusStale {
+		t.Error("stale status not reported", snap.StreamStatus)
+	}
+	c.Apply(testMcm(t, `{"op":"mcm","mc":[{"id":"1.2","tv":6}]}`))
+	snaps := c.Snapshots()
+	if len(snaps) != 1 || snaps[0].StreamStatus != 0 ||
+		snaps[0].TotalMatched != 6 {
+		t.Error("stale status not cleared", snaps)
+	}
+}
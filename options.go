@@ -0,0 +1,189 @@
+package betfair
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// construction settings collected from options
+type options struct {
+	endpoints *Endpoints
+	client    *http.Client
+	transport http.RoundTripper
+	timeout   time.Duration
+	logger    Logger
+	appKey    string
+	noLogin   bool
+	rootCAs   *x509.CertPool
+}
+
+// Option configures a Session before it logs in
+type Option func(o *options) error
+
+// Sends requests to e instead of the built-in endpoints of the credentials
+// exchange, e.g. a local mock or a recording proxy
+func WithEndpoints(e Endpoints) Option {
+	return func(o *options) error {
+		if e.Identity == "" || e.Betting == "" || e.Account == "" {
+			return errors.New("identity, betting and account endpoints are " +
+				"required")
+		}
+		o.endpoints = &e
+		return nil
+	}
+}
+
+// Sends requests with a copy of c, e.g. one with an instrumented transport
+// or proxy settings
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) error {
+		if c == nil {
+			return errors.New("http client can not be nil")
+		}
+		o.client = c
+		return nil
+	}
+}
+
+// Replaces the transport of the http client. Certificate login needs an
+// *http.Transport to attach the client certificate to.
+func WithTransport(t http.RoundTripper) Option {
+	return func(o *options) error {
+		if t == nil {
+			return errors.New("transport can not be nil")
+		}
+		o.transport = t
+		return nil
+	}
+}
+
+// Limits the time of every request including reading the response body
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return errors.New("timeout can not be negative")
+		}
+		o.timeout = d
+		return nil
+	}
+}
+
+// Verifies server certificates against pool instead of the system roots,
+// e.g. to trust a local stand-in. Needs an *http.Transport if WithTransport
+// or WithHTTPClient is also given.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *options) error {
+		if pool == nil {
+			return errors.New("root CA pool can not be nil")
+		}
+		o.rootCAs = pool
+		return nil
+	}
+}
+
+// Logs through l, nil discards every record
+func WithLogger(l Logger) Option {
+	return func(o *options) error {
+		if l == nil {
+			l = NewNopLogger()
+		}
+		o.logger = l
+		return nil
+	}
+}
+
+// Sends key as X-Application header instead of the credentials application
+// key, cert login sessions don't need SetUsedApplication then
+func WithAppKey(key string) Option {
+	return func(o *options) error {
+		o.appKey = key
+		return nil
+	}
+}
+
+// Skips logging in on construction. The first request rejected with
+// NO_SESSION logs in, so this makes the login lazy and allows sessions
+// talking to test doubles without an identity endpoint.
+func WithoutLogin() Option {
+	return func(o *options) error {
+		o.noLogin = true
+		return nil
+	}
+}
+
+// Returns a Session configured by opts, logged in unless WithoutLogin is
+// given
+func New(ctx context.Context, credentials Credentials,
+	opts ...Option) (*Session, error) {
+	var o options
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	if credentials == nil {
+		return nil, errors.New("credentials can not be nil")
+	}
+
+	session := &Session{
+		credentials: credentials,
+		appKey:      credentials.AppKey(),
+		endpoints:   o.endpoints,
+		logger:      o.logger,
+	}
+	if session.logger == nil {
+		session.logger = newDefaultLogger(os.Stderr)
+	}
+	if o.appKey != "" {
+		session.appKey = o.appKey
+	}
+
+	if session.endpoints == nil {
+		e, err := credentials.DefaultEndpoints()
+		if err != nil {
+			return nil, err
+		}
+		session.endpoints = &e
+	}
+
+	// set client
+	base := &http.Client{}
+	if o.client != nil {
+		*base = *o.client
+	}
+	if o.transport != nil {
+		base.Transport = o.transport
+	}
+	if o.timeout != 0 {
+		base.Timeout = o.timeout
+	}
+	if o.rootCAs != nil {
+		transport, err := cloneTransport(base.Transport)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = o.rootCAs
+		base.Transport = transport
+	}
+	session.httpClient = base
+
+	client, err := getHttpClient(session.credentials, base)
+	if err != nil {
+		return nil, err
+	}
+	session.loginClient = client
+
+	if o.noLogin {
+		return session, nil
+	}
+	if err := session.login(ctx); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
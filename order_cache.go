@@ -0,0 +1,203 @@
+package betfair
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderRunnerSnapshot is the cached orders and matched ladders of a runner,
+// orders are sorted by placed date
+type OrderRunnerSnapshot struct {
+	SelectionId  uint32
+	Handicap     float64
+	Orders       []UnmatchedOrder
+	MatchedBacks []PriceSize
+	MatchedLays  []PriceSize
+}
+
+// OrderMarketSnapshot is the cached orders of a market, runners are sorted
+// by selection id
+type OrderMarketSnapshot struct {
+	MarketId    string
+	AccountId   int64
+	Closed      bool
+	PublishTime time.Time
+	Runners     []OrderRunnerSnapshot
+}
+
+// OrderCache rebuilds the orders of the account from the deltas of order
+// change messages. Orders are keyed by market, selection and bet id. It is
+// safe for concurrent use.
+type OrderCache struct {
+	mu      sync.RWMutex
+	markets map[string]*orderMarketState
+}
+
+// cached orders of a market
+type orderMarketState struct {
+	accountId int64
+	closed    bool
+	pt        time.Time
+	runners   map[runnerKey]*orderRunnerState
+}
+
+// cached orders of a runner by bet id and matched ladders by price
+type orderRunnerState struct {
+	orders map[string]UnmatchedOrder
+	mb, ml map[float64]float64
+}
+
+// Returns an empty order cache
+func NewOrderCache() *OrderCache {
+	return &OrderCache{markets: make(map[string]*orderMarketState)}
+}
+
+// Applies the changes of msg, returns ids of the changed markets
+func (c *OrderCache) Apply(msg *OrderChangeMessage) []string {
+	if msg == nil || len(msg.Oc) == 0 {
+		return nil
+	}
+	pt := time.UnixMilli(msg.Pt)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make([]string, 0, len(msg.Oc))
+	for i := range msg.Oc {
+		oc := &msg.Oc[i]
+		m := c.markets[oc.Id]
+		if m == nil || oc.FullImage {
+			m = &orderMarketState{
+				runners: make(map[runnerKey]*orderRunnerState),
+			}
+			c.markets[oc.Id] = m
+		}
+		m.apply(oc)
+		m.pt = pt
+		ids = append(ids, oc.Id)
+	}
+	return ids
+}
+
+// Returns the order with betId in the market
+func (c *OrderCache) Order(marketId string, selectionId uint32,
+	betId string) (UnmatchedOrder, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m := c.markets[marketId]
+	if m == nil {
+		return UnmatchedOrder{}, false
+	}
+	for key, r := range m.runners {
+		if key.id != selectionId {
+			continue
+		}
+		if o, ok := r.orders[betId]; ok {
+			return o, true
+		}
+	}
+	return UnmatchedOrder{}, false
+}
+
+// Returns the cached orders of the market
+func (c *OrderCache) Snapshot(marketId string) (OrderMarketSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return OrderMarketSnapshot{}, false
+	}
+	return m.snapshot(marketId), true
+}
+
+// Returns the cached orders of every market ordered by market id
+func (c *OrderCache) Snapshots() []OrderMarketSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.markets))
+	for id := range c.markets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	snapshots := make([]OrderMarketSnapshot, len(ids))
+	for i, id := range ids {
+		snapshots[i] = c.markets[id].snapshot(id)
+	}
+	return snapshots
+}
+
+// Drops the orders of the market, e.g. once it is closed
+func (c *OrderCache) Remove(marketId string) {
+	c.mu.Lock()
+	delete(c.markets, marketId)
+	c.mu.Unlock()
+}
+
+func (m *orderMarketState) apply(oc *OrderMarketChange) {
+	if oc.AccountId != 0 {
+		m.accountId = oc.AccountId
+	}
+	if oc.Closed {
+		m.closed = true
+	}
+
+	for i := range oc.Orc {
+		orc := &oc.Orc[i]
+		key := runnerKey{orc.Id, orc.Hc}
+		r := m.runners[key]
+		if r == nil || orc.FullImage {
+			r = &orderRunnerState{orders: make(map[string]UnmatchedOrder)}
+			m.runners[key] = r
+		}
+
+		for _, o := range orc.Uo {
+			r.orders[o.BetId] = o
+		}
+		r.mb = updateLadder(r.mb, orc.Mb)
+		r.ml = updateLadder(r.ml, orc.Ml)
+	}
+}
+
+func (m *orderMarketState) snapshot(marketId string) OrderMarketSnapshot {
+	snap := OrderMarketSnapshot{
+		MarketId:    marketId,
+		AccountId:   m.accountId,
+		Closed:      m.closed,
+		PublishTime: m.pt,
+		Runners:     make([]OrderRunnerSnapshot, 0, len(m.runners)),
+	}
+
+	for key, r := range m.runners {
+		runner := OrderRunnerSnapshot{
+			SelectionId:  key.id,
+			Handicap:     key.hc,
+			Orders:       make([]UnmatchedOrder, 0, len(r.orders)),
+			MatchedBacks: ladderPrices(r.mb, true),
+			MatchedLays:  ladderPrices(r.ml, false),
+		}
+		for _, o := range r.orders {
+			runner.Orders = append(runner.Orders, o)
+		}
+		sort.Slice(runner.Orders, func(i, j int) bool {
+			a, b := runner.Orders[i], runner.Orders[j]
+			if a.PlacedDate != b.PlacedDate {
+				return a.PlacedDate < b.PlacedDate
+			}
+			return a.BetId < b.BetId
+		})
+		snap.Runners = append(snap.Runners, runner)
+	}
+	sort.Slice(snap.Runners, func(i, j int) bool {
+		a, b := snap.Runners[i], snap.Runners[j]
+		if a.SelectionId != b.SelectionId {
+			return a.SelectionId < b.SelectionId
+		}
+		return a.Handicap < b.Handicap
+	})
+	return snap
+}
@@ -0,0 +1,59 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// decodes an order change message
+func testOcm(t *testing.T, s string) *OrderChangeMessage {
+	var msg OrderChangeMessage
+	if err := json.Unmarshal([]byte(s), &msg); err != nil {
+		t.Fatal(err)
+	}
+	return &msg
+}
+
+func Test_OrderCache(t *testing.T) {
+	c := NewOrderCache()
+	c.Apply(testOcm(t, `{"op":"ocm","pt":1500000000000,"ct":"SUB_IMAGE",`+
+		`"oc":[{"id":"1.2","accountId":7,"fullImage":true,"orc":[{"id":10,`+
+		`"fullImage":true,"uo":[{"id":"b1","p":2.5,"s":10,"side":"B",`+
+		`"status":"E","pt":"L","ot":"L","pd":1,"sm":0,"sr":10}]}]}]}`))
+
+	// the bet is matched
+	c.Apply(testOcm(t, `{"op":"ocm","pt":1500000000100,"oc":[{"id":"1.2",`+
+		`"orc":[{"id":10,"uo":[{"id":"b1","p":2.5,"s":10,"side":"B",`+
+		`"status":"EC","pt":"L","ot":"L","pd":1,"md":2,"avp":2.5,"sm":10,`+
+		`"sr":0}],"mb":[[2.5,10]]},{"id":11,"uo":[{"id":"b2","p":3,"s":4,`+
+		`"side":"L","status":"E","pd":3,"sr":4}]}]}]}`))
+
+	o, ok := c.Order("1.2", 10, "b1")
+	if !ok {
+		t.Fatal("order not cached")
+	}
+	if o.Status != StreamOrderStatusExecutionComplete || o.SizeMatched != 10 ||
+		o.AveragePriceMatched != 2.5 || o.Side != StreamSideBack {
+		t.Error("order not updated", o)
+	}
+	if _, ok := c.Order("1.2", 11, "b1"); ok {
+		t.Error("order found under wrong selection")
+	}
+
+	snap, _ := c.Snapshot("1.2")
+	if snap.AccountId != 7 || len(snap.Runners) != 2 ||
+		snap.Runners[0].SelectionId != 10 ||
+		snap.Runners[0].MatchedBacks[0] != (PriceSize{2.5, 10}) ||
+		snap.Runners[1].Orders[0].BetId != "b2" {
+		t.Error("snapshot wrong", snap)
+	}
+
+	// a runner image replaces its orders, closing the market is kept
+	c.Apply(testOcm(t, `{"op":"ocm","oc":[{"id":"1.2","closed":true,`+
+		`"orc":[{"id":11,"fullImage":true,"uo":[]}]}]}`))
+	snap, _ = c.Snapshot("1.2")
+	if !snap.Closed || len(snap.Runners[1].Orders) != 0 ||
+		len(snap.Runners[0].Orders) != 1 {
+		t.Error("runner image not applied", snap)
+	}
+}
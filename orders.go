@@ -0,0 +1,338 @@
+package betfair
+
+import (
+	"context"
+	"time"
+)
+
+// Order types
+const (
+	OrderTypeLimit         = "LIMIT"
+	OrderTypeLimitOnClose  = "LIMIT_ON_CLOSE"
+	OrderTypeMarketOnClose = "MARKET_ON_CLOSE"
+)
+
+// Sides
+const (
+	SideBack = "BACK"
+	SideLay  = "LAY"
+)
+
+// Persistence types
+const (
+	PersistenceLapse         = "LAPSE"
+	PersistencePersist       = "PERSIST"
+	PersistenceMarketOnClose = "MARKET_ON_CLOSE"
+)
+
+// Time in force
+const (
+	TimeInForceFillOrKill = "FILL_OR_KILL"
+)
+
+// Execution and instruction report statuses
+const (
+	ReportStatusSuccess             = "SUCCESS"
+	ReportStatusFailure             = "FAILURE"
+	ReportStatusProcessedWithErrors = "PROCESSED_WITH_ERRORS"
+	ReportStatusTimeout             = "TIMEOUT"
+)
+
+// Execution report error codes
+const (
+	ExecErrorInMatcher                  = "ERROR_IN_MATCHER"
+	ExecErrorProcessedWithErrors        = "PROCESSED_WITH_ERRORS"
+	ExecErrorBetActionError             = "BET_ACTION_ERROR"
+	ExecErrorInvalidAccountState        = "INVALID_ACCOUNT_STATE"
+	ExecErrorInvalidWalletStatus        = "INVALID_WALLET_STATUS"
+	ExecErrorInsufficientFunds          = "INSUFFICIENT_FUNDS"
+	ExecErrorLossLimitExceeded          = "LOSS_LIMIT_EXCEEDED"
+	ExecErrorMarketSuspended            = "MARKET_SUSPENDED"
+	ExecErrorMarketNotOpenForBetting    = "MARKET_NOT_OPEN_FOR_BETTING"
+	ExecErrorDuplicateTransaction       = "DUPLICATE_TRANSACTION"
+	ExecErrorInvalidOrder               = "INVALID_ORDER"
+	ExecErrorInvalidMarketId            = "INVALID_MARKET_ID"
+	ExecErrorPermissionDenied           = "PERMISSION_DENIED"
+	ExecErrorDuplicateBetIds            = "DUPLICATE_BETIDS"
+	ExecErrorNoActionRequired           = "NO_ACTION_REQUIRED"
+	ExecErrorServiceUnavailable         = "SERVICE_UNAVAILABLE"
+	ExecErrorRejectedByRegulator        = "REJECTED_BY_REGULATOR"
+	ExecErrorNoChasing                  = "NO_CHASING"
+	ExecErrorRegulatorIsNotAvailable    = "REGULATOR_IS_NOT_AVAILABLE"
+	ExecErrorTooManyInstructions        = "TOO_MANY_INSTRUCTIONS"
+	ExecErrorInvalidMarketVersion       = "INVALID_MARKET_VERSION"
+	ExecErrorInvalidProfitRatio         = "INVALID_PROFIT_RATIO"
+	ExecErrorEventExposureLimitExceeded = "EVENT_EXPOSURE_LIMIT_EXCEEDED"
+)
+
+// Instruction report error codes
+const (
+	InstErrorInvalidBetSize                    = "INVALID_BET_SIZE"
+	InstErrorInvalidRunner                     = "INVALID_RUNNER"
+	InstErrorBetTakenOrLapsed                  = "BET_TAKEN_OR_LAPSED"
+	InstErrorBetInProgress                     = "BET_IN_PROGRESS"
+	InstErrorRunnerRemoved                     = "RUNNER_REMOVED"
+	InstErrorMarketNotOpenForBetting           = "MARKET_NOT_OPEN_FOR_BETTING"
+	InstErrorLossLimitExceeded                 = "LOSS_LIMIT_EXCEEDED"
+	InstErrorMarketNotOpenForBspBetting        = "MARKET_NOT_OPEN_FOR_BSP_BETTING"
+	InstErrorInvalidPriceEdit                  = "INVALID_PRICE_EDIT"
+	InstErrorInvalidOdds                       = "INVALID_ODDS"
+	InstErrorInsufficientFunds                 = "INSUFFICIENT_FUNDS"
+	InstErrorInvalidPersistenceType            = "INVALID_PERSISTENCE_TYPE"
+	InstErrorErrorInMatcher                    = "ERROR_IN_MATCHER"
+	InstErrorInvalidBackLayCombination         = "INVALID_BACK_LAY_COMBINATION"
+	InstErrorErrorInOrder                      = "ERROR_IN_ORDER"
+	InstErrorInvalidBidType                    = "INVALID_BID_TYPE"
+	InstErrorInvalidBetId                      = "INVALID_BET_ID"
+	InstErrorCancelledNotPlaced                = "CANCELLED_NOT_PLACED"
+	InstErrorRelatedActionFailed               = "RELATED_ACTION_FAILED"
+	InstErrorNoActionRequired                  = "NO_ACTION_REQUIRED"
+	InstErrorTimeInForceConflict               = "TIME_IN_FORCE_CONFLICT"
+	InstErrorUnexpectedPersistenceType         = "UNEXPECTED_PERSISTENCE_TYPE"
+	InstErrorInvalidOrderType                  = "INVALID_ORDER_TYPE"
+	InstErrorUnexpectedMinFillSize             = "UNEXPECTED_MIN_FILL_SIZE"
+	InstErrorInvalidCustomerOrderRef           = "INVALID_CUSTOMER_ORDER_REF"
+	InstErrorInvalidMinFillSize                = "INVALID_MIN_FILL_SIZE"
+	InstErrorBetLapsedPriceImprovementTooLarge = "BET_LAPSED_PRICE_IMPROVEMENT_TOO_LARGE"
+)
+
+// Order statuses
+const (
+	OrderStatusPending           = "PENDING"
+	OrderStatusExecutionComplete = "EXECUTION_COMPLETE"
+	OrderStatusExecutable        = "EXECUTABLE"
+	OrderStatusExpired           = "EXPIRED"
+)
+
+// Instructions is implemented by the instruction lists of order operations
+type Instructions interface {
+	operation() string
+	len() int
+}
+
+// Limit Order, PersistenceType is left empty when TimeInForce is set
+type LimitOrder struct {
+	Size            float64 `json:"size,omitempty"`
+	Price           float64 `json:"price"`
+	PersistenceType string  `json:"persistenceType,omitempty"`
+	TimeInForce     string  `json:"timeInForce,omitempty"`
+	MinFillSize     float64 `json:"minFillSize,omitempty"`
+	BetTargetType   string  `json:"betTargetType,omitempty"`
+	BetTargetSize   float64 `json:"betTargetSize,omitempty"`
+}
+
+// Limit On Close Order (BSP bet with a price limit)
+type LimitOnCloseOrder struct {
+	Liability float64 `json:"liability"`
+	Price     float64 `json:"price"`
+}
+
+// Market On Close Order (BSP bet without a price limit)
+type MarketOnCloseOrder struct {
+	Liability float64 `json:"liability"`
+}
+
+// Place Instruction
+type PlaceInstruction struct {
+	OrderType          string              `json:"orderType"`
+	SelectionId        uint32              `json:"selectionId"`
+	Handicap           float64             `json:"handicap,omitempty"`
+	Side               string              `json:"side"`
+	LimitOrder         *LimitOrder         `json:"limitOrder,omitempty"`
+	LimitOnCloseOrder  *LimitOnCloseOrder  `json:"limitOnCloseOrder,omitempty"`
+	MarketOnCloseOrder *MarketOnCloseOrder `json:"marketOnCloseOrder,omitempty"`
+	CustomerOrderRef   string              `json:"customerOrderRef,omitempty"`
+}
+
+// Instruction list of placeOrders
+type PlaceInstructions []PlaceInstruction
+
+func (PlaceInstructions) operation() string { return "placeOrders" }
+
+func (i PlaceInstructions) len() int { return len(i) }
+
+// Cancel Instruction, SizeReduction cancels only part of the remaining size
+type CancelInstruction struct {
+	BetId         string  `json:"betId"`
+	SizeReduction float64 `json:"sizeReduction,omitempty"`
+}
+
+// Instruction list of cancelOrders
+type CancelInstructions []CancelInstruction
+
+func (CancelInstructions) operation() string { return "cancelOrders" }
+
+func (i CancelInstructions) len() int { return len(i) }
+
+// Replace Instruction, cancels the bet and places a new one at NewPrice
+type ReplaceInstruction struct {
+	BetId    string  `json:"betId"`
+	NewPrice float64 `json:"newPrice"`
+}
+
+// Instruction list of replaceOrders
+type ReplaceInstructions []ReplaceInstruction
+
+func (ReplaceInstructions) operation() string { return "replaceOrders" }
+
+func (i ReplaceInstructions) len() int { return len(i) }
+
+// Update Instruction, changes persistence type of an unmatched bet
+type UpdateInstruction struct {
+	BetId              string `json:"betId"`
+	NewPersistenceType string `json:"newPersistenceType"`
+}
+
+// Instruction list of updateOrders
+type UpdateInstructions []UpdateInstruction
+
+func (UpdateInstructions) operation() string { return "updateOrders" }
+
+func (i UpdateInstructions) len() int { return len(i) }
+
+// Market Version, used to reject orders if the market has moved on
+type MarketVersion struct {
+	Version int64 `json:"version"`
+}
+
+// Place Instruction Report
+type PlaceInstructionReport struct {
+	Status              string
+	ErrorCode           string
+	OrderStatus         string
+	Instruction         PlaceInstruction
+	BetId               string
+	PlacedDate          time.Time
+	AveragePriceMatched float64
+	SizeMatched         float64
+}
+
+// Place Execution Report
+type PlaceExecutionReport struct {
+	CustomerRef        string
+	Status             string
+	ErrorCode          string
+	MarketId           string
+	InstructionReports []PlaceInstructionReport
+}
+
+// Cancel Instruction Report
+type CancelInstructionReport struct {
+	Status        string
+	ErrorCode     string
+	Instruction   CancelInstruction
+	SizeCancelled float64
+	CancelledDate time.Time
+}
+
+// Cancel Execution Report
+type CancelExecutionReport struct {
+	CustomerRef        string
+	Status             string
+	ErrorCode          string
+	MarketId           string
+	InstructionReports []CancelInstructionReport
+}
+
+// Replace Instruction Report
+type ReplaceInstructionReport struct {
+	Status                  string
+	ErrorCode               string
+	CancelInstructionReport CancelInstructionReport
+	PlaceInstructionReport  PlaceInstructionReport
+}
+
+// Replace Execution Report
+type ReplaceExecutionReport struct {
+	CustomerRef        string
+	Status             string
+	ErrorCode          string
+	MarketId           string
+	InstructionReports []ReplaceInstructionReport
+}
+
+// Update Instruction Report
+type UpdateInstructionReport struct {
+	Status      string
+	ErrorCode   string
+	Instruction UpdateInstruction
+}
+
+// Update Execution Report
+type UpdateExecutionReport struct {
+	CustomerRef        string
+	Status             string
+	ErrorCode          string
+	MarketId           string
+	InstructionReports []UpdateInstructionReport
+}
+
+// Places new orders into a market, see PlaceOrdersRequest. A Query needs
+// MarketId and Instructions as PlaceInstructions.
+func (s *Session) PlaceOrders(r Request, fn ...VisitorFunc) (
+	*PlaceExecutionReport, error) {
+	return s.PlaceOrdersContext(context.Background(), r, fn...)
+}
+
+// Like PlaceOrders, ctx controls the request
+func (s *Session) PlaceOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) (*PlaceExecutionReport, error) {
+	var report PlaceExecutionReport
+	if err := betRequest(ctx, "placeOrders", s, r, &report, fn...); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Cancels all or part of unmatched orders, see CancelOrdersRequest. A Query
+// with Instructions (as CancelInstructions) needs MarketId.
+func (s *Session) CancelOrders(r Request, fn ...VisitorFunc) (
+	*CancelExecutionReport, error) {
+	return s.CancelOrdersContext(context.Background(), r, fn...)
+}
+
+// Like CancelOrders, ctx controls the request
+func (s *Session) CancelOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) (*CancelExecutionReport, error) {
+	var report CancelExecutionReport
+	if err := betRequest(ctx, "cancelOrders", s, r, &report, fn...); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Cancels bets and places them again at a new price, every replaced bet gets
+// a new bet id, see ReplaceOrdersRequest. A Query needs MarketId and
+// Instructions as ReplaceInstructions.
+func (s *Session) ReplaceOrders(r Request, fn ...VisitorFunc) (
+	*ReplaceExecutionReport, error) {
+	return s.ReplaceOrdersContext(context.Background(), r, fn...)
+}
+
+// Like ReplaceOrders, ctx controls the request
+func (s *Session) ReplaceOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) (*ReplaceExecutionReport, error) {
+	var report ReplaceExecutionReport
+	if err := betRequest(ctx, "replaceOrders", s, r, &report, fn...); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Updates non-exposure changing fields (persistence type) of unmatched bets,
+// see UpdateOrdersRequest. A Query needs MarketId and Instructions as
+// UpdateInstructions.
+func (s *Session) UpdateOrders(r Request, fn ...VisitorFunc) (
+	*UpdateExecutionReport, error) {
+	return s.UpdateOrdersContext(context.Background(), r, fn...)
+}
+
+// Like UpdateOrders, ctx controls the request
+func (s *Session) UpdateOrdersContext(ctx context.Context, r Request,
+	fn ...VisitorFunc) (*UpdateExecutionReport, error) {
+	var report UpdateExecutionReport
+	if err := betRequest(ctx, "updateOrders", s, r, &report, fn...); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,242 @@
+package betfair
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_PlaceOrders(t *testing.T) {
+	s := newMethodTestServer(t, "/betting/", testMethods{
+		"placeOrders": func(p map[string]interface{}) string {
+			inst, _ := p["instructions"].([]interface{})
+			if p["marketId"] != "1.1" || p["customerRef"] != "ref" ||
+				len(inst) != 1 {
+				t.Error("unexpected params", p)
+				return `{}`
+			}
+			i := inst[0].(map[string]interface{})
+			limit, _ := i["limitOrder"].(map[string]interface{})
+			if i["orderType"] != OrderTypeLimit || i["side"] != SideBack ||
+				i["selectionId"] != float64(47972) || limit["size"] != 2.0 ||
+				limit["price"] != 3.5 || limit["persistenceType"] != "LAPSE" {
+				t.Error("unexpected instruction", i)
+			}
+			return `{"customerRef":"ref","status":"SUCCESS","marketId":"1.1",` +
+				`"instructionReports":[{"status":"SUCCESS",` +
+				`"orderStatus":"EXECUTION_COMPLETE","instruction":` +
+				`{"selectionId":47972,"side":"BACK","orderType":"LIMIT",` +
+				`"limitOrder":{"size":2,"price":3.5,"persistenceType":"LAPSE"}},` +
+				`"betId":"31","placedDate":"2024-01-02T10:00:00.000Z",` +
+				`"averagePriceMatched":3.6,"sizeMatched":2}]}`
+		},
+	})
+
+	instructions := PlaceInstructions{{
+		OrderType:   OrderTypeLimit,
+		SelectionId: 47972,
+		Side:        SideBack,
+		LimitOrder: &LimitOrder{
+			Size:            2,
+			Price:           3.5,
+			PersistenceType: PersistenceLapse,
+		},
+	}}
+
+	for _, r := range []Request{
+		&PlaceOrdersRequest{MarketId: "1.1", Instructions: instructions,
+			CustomerRef: "ref"},
+		&Query{MarketId: "1.1", Instructions: instructions, CustomerRef: "ref"},
+	} {
+		report, err := s.PlaceOrders(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Status != ReportStatusSuccess || report.MarketId != "1.1" ||
+			len(report.InstructionReports) != 1 {
+			t.Fatal("report not decoded", report)
+		}
+		ir := report.InstructionReports[0]
+		if ir.BetId != "31" || ir.SizeMatched != 2 ||
+			ir.AveragePriceMatched != 3.6 ||
+			ir.OrderStatus != OrderStatusExecutionComplete ||
+			ir.Instruction.LimitOrder == nil ||
+			ir.Instruction.LimitOrder.Price != 3.5 ||
+			!ir.PlacedDate.Equal(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)) {
+			t.Error("instruction report not decoded", ir)
+		}
+	}
+}
+
+func Test_PlaceOrdersValidate(t *testing.T) {
+	s := &Session{}
+	for _, r := range []Request{
+		&PlaceOrdersRequest{MarketId: "1.1"},
+		&PlaceOrdersRequest{Instructions: PlaceInstructions{{}}},
+		&PlaceOrdersRequest{MarketId: "1.1",
+			Instructions: make(PlaceInstructions, maxPlaceInstructions+1)},
+		&Query{MarketId: "1.1"},
+		&Query{MarketId: "1.1", Instructions: PlaceInstructions(nil)},
+		&Query{MarketId: "1.1", Instructions: PlaceInstructions{}},
+		&Query{MarketId: "1.1", Instructions: CancelInstructions{{}}},
+		&Query{Instructions: PlaceInstructions{{}}},
+		// orders not matching their order type
+		&PlaceOrdersRequest{MarketId: "1.1", Instructions: PlaceInstructions{
+			{OrderType: OrderTypeLimit}}},
+		&PlaceOrdersRequest{MarketId: "1.1", Instructions: PlaceInstructions{
+			{OrderType: OrderTypeLimit, LimitOrder: &LimitOrder{Price: 2}},
+			{OrderType: OrderTypeLimitOnClose, LimitOrder: &LimitOrder{Price: 2}}}},
+		&PlaceOrdersRequest{MarketId: "1.1", Instructions: PlaceInstructions{
+			{LimitOrder: &LimitOrder{Price: 2}}}},
+		&Query{MarketId: "1.1", Instructions: PlaceInstructions{
+			{OrderType: OrderTypeMarketOnClose,
+				LimitOnCloseOrder: &LimitOnCloseOrder{Liability: 2}}}},
+	} {
+		if _, err := s.PlaceOrders(r); err == nil {
+			t.Errorf("invalid request accepted %#v", r)
+		}
+	}
+}
+
+func Test_OrderRequestsMarshal(t *testing.T) {
+	for _, c := range []struct {
+		r    Request
+		want string
+	}{
+		{&PlaceOrdersRequest{MarketId: "1.1", Instructions: PlaceInstructions{{
+			OrderType: OrderTypeLimit, SelectionId: 1, Side: SideBack,
+			LimitOrder: &LimitOrder{Size: 2, Price: 3.5,
+				TimeInForce: TimeInForceFillOrKill}}}},
+			`{"marketId":"1.1","instructions":[{"orderType":"LIMIT",` +
+				`"selectionId":1,"side":"BACK","limitOrder":{"size":2,` +
+				`"price":3.5,"timeInForce":"FILL_OR_KILL"}}]}`},
+		{&CancelOrdersRequest{}, `{}`},
+		{&CancelOrdersRequest{MarketId: "1.1", Instructions: CancelInstructions{
+			{BetId: "31", SizeReduction: 1.5}, {BetId: "32"}}},
+			`{"marketId":"1.1","instructions":[{"betId":"31",` +
+				`"sizeReduction":1.5},{"betId":"32"}]}`},
+		{&ReplaceOrdersRequest{MarketId: "1.1", Instructions: ReplaceInstructions{
+			{BetId: "31", NewPrice: 4.2}},
+			MarketVersion: &MarketVersion{Version: 7}},
+			`{"marketId":"1.1","instructions":[{"betId":"31","newPrice":4.2}],` +
+				`"marketVersion":{"version":7}}`},
+		{&UpdateOrdersRequest{MarketId: "1.1", Instructions: UpdateInstructions{
+			{BetId: "31", NewPersistenceType: PersistencePersist}},
+			CustomerRef: "ref"},
+			`{"marketId":"1.1","instructions":[{"betId":"31",` +
+				`"newPersistenceType":"PERSIST"}],"customerRef":"ref"}`},
+	} {
+		p, err := json.Marshal(c.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != c.want {
+			t.Errorf("unexpected payload %s, want %s", p, c.want)
+		}
+	}
+}
+
+func Test_OrderReportsRoundTrip(t *testing.T) {
+	cancelled := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	for _, report := range []interface{}{
+		&CancelExecutionReport{Status: ReportStatusSuccess, MarketId: "1.1",
+			InstructionReports: []CancelInstructionReport{{
+				Status:        ReportStatusSuccess,
+				Instruction:   CancelInstruction{BetId: "31"},
+				SizeCancelled: 2,
+				CancelledDate: cancelled,
+			}}},
+		&ReplaceExecutionReport{Status: ReportStatusFailure,
+			ErrorCode: ExecErrorBetActionError, MarketId: "1.1",
+			InstructionReports: []ReplaceInstructionReport{{
+				Status:    ReportStatusFailure,
+				ErrorCode: InstErrorCancelledNotPlaced,
+				CancelInstructionReport: CancelInstructionReport{
+					Status:      ReportStatusSuccess,
+					Instruction: CancelInstruction{BetId: "31"},
+				},
+				PlaceInstructionReport: PlaceInstructionReport{
+					Status:    ReportStatusFailure,
+					ErrorCode: InstErrorInvalidOdds,
+				},
+			}}},
+		&UpdateExecutionReport{Status: ReportStatusSuccess, MarketId: "1.1",
+			InstructionReports: []UpdateInstructionReport{{
+				Status: ReportStatusSuccess,
+				Instruction: UpdateInstruction{BetId: "31",
+					NewPersistenceType: PersistencePersist},
+			}}},
+	} {
+		p, err := json.Marshal(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := reflect.New(reflect.TypeOf(report).Elem()).Interface()
+		if err := json.Unmarshal(p, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, report) {
+			t.Errorf("round trip changed report %+v, want %+v", decoded, report)
+		}
+	}
+}
+
+func Test_CancelReplaceUpdateOrders(t *testing.T) {
+	s := newMethodTestServer(t, "/betting/", testMethods{
+		"cancelOrders": func(p map[string]interface{}) string {
+			if len(p) != 0 {
+				t.Error("cancel all sent params", p)
+			}
+			return `{"status":"SUCCESS","instructionReports":[]}`
+		},
+		"replaceOrders": func(p map[string]interface{}) string {
+			inst, _ := p["instructions"].([]interface{})
+			if p["marketId"] != "1.1" || len(inst) != 1 {
+				t.Error("unexpected params", p)
+			}
+			return `{"status":"SUCCESS","marketId":"1.1","instructionReports":` +
+				`[{"status":"SUCCESS","cancelInstructionReport":{"status":` +
+				`"SUCCESS","instruction":{"betId":"31"},"sizeCancelled":2},` +
+				`"placeInstructionReport":{"status":"SUCCESS","betId":"32",` +
+				`"sizeMatched":0}}]}`
+		},
+		"updateOrders": func(p map[string]interface{}) string {
+			return `{"status":"FAILURE","errorCode":"BET_ACTION_ERROR",` +
+				`"marketId":"1.1","instructionReports":[{"status":"FAILURE",` +
+				`"errorCode":"BET_TAKEN_OR_LAPSED","instruction":{"betId":"31",` +
+				`"newPersistenceType":"PERSIST"}}]}`
+		},
+	})
+
+	cancel, err := s.CancelOrders(&CancelOrdersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel.Status != ReportStatusSuccess {
+		t.Error("cancel report not decoded", cancel)
+	}
+
+	replace, err := s.ReplaceOrders(&ReplaceOrdersRequest{MarketId: "1.1",
+		Instructions: ReplaceInstructions{{BetId: "31", NewPrice: 4.2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replace.InstructionReports) != 1 ||
+		replace.InstructionReports[0].CancelInstructionReport.SizeCancelled != 2 ||
+		replace.InstructionReports[0].PlaceInstructionReport.BetId != "32" {
+		t.Error("replace report not decoded", replace)
+	}
+
+	update, err := s.UpdateOrders(&Query{MarketId: "1.1",
+		Instructions: UpdateInstructions{{BetId: "31",
+			NewPersistenceType: PersistencePersist}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.ErrorCode != ExecErrorBetActionError ||
+		len(update.InstructionReports) != 1 ||
+		update.InstructionReports[0].ErrorCode != InstErrorBetTakenOrLapsed {
+		t.Error("update report not decoded", update)
+	}
+}
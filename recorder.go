@@ -0,0 +1,257 @@
+package betfair
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Replay speeds, any other positive speed is a multiple of real time
+const (
+	ReplayAsFastAsPossible = 0
+	ReplayRealTime         = 1
+)
+
+// Recorder writes raw stream lines with their receive time to a gzip
+// compressed recording, one "<unix nanos>\t<line>" record per line. It is
+// safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	gz     *gzip.Writer
+	closer io.Closer // closed by Close if set
+	err    error
+}
+
+// Returns a recorder writing to w, Close flushes it but leaves w open
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{gz: gzip.NewWriter(w)}
+}
+
+// Returns a recorder writing to a new file at path, Close closes the file
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorder(f)
+	r.closer = f
+	return r, nil
+}
+
+// Writes line received at t, the first error is returned by every later
+// call
+func (r *Recorder) Record(t time.Time, line []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+
+	buf := make([]byte, 0, len(line)+21)
+	buf = strconv.AppendInt(buf, t.UnixNano(), 10)
+	buf = append(buf, '\t')
+	buf = append(buf, line...)
+	buf = append(buf, '\n')
+	if _, err := r.gz.Write(buf); err != nil {
+		r.err = fmt.Errorf("recorder: %w", err)
+	}
+	return r.err
+}
+
+// Flushes buffered records to the underlying writer
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		if err := r.gz.Flush(); err != nil {
+			r.err = fmt.Errorf("recorder: %w", err)
+		}
+	}
+	return r.err
+}
+
+// Completes the recording and closes the file of CreateRecorder
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.gz.Close()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if r.err == nil {
+		r.err = errors.New("recorder: closed")
+	}
+	return err
+}
+
+// Replayer delivers the change messages of a recording as Stream would,
+// segments joined and heartbeats dropped unless they change the stale
+// status, in recorded order. Messages are
+// spaced by their recorded receive times divided by the speed.
+type Replayer struct {
+	r      *bufio.Reader
+	gz     *gzip.Reader
+	closer io.Closer // closed by Close if set
+	speed  float64
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// Returns a replayer reading the recording from r at speed, e.g.
+// ReplayRealTime, 10 for ten times faster or ReplayAsFastAsPossible
+func NewReplayer(r io.Reader, speed float64) (*Replayer, error) {
+	if speed < 0 {
+		return nil, errors.New("replay speed can not be negative")
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("replayer: %w", err)
+	}
+	return &Replayer{
+		r:     bufio.NewReader(gz),
+		gz:    gz,
+		speed: speed,
+		sleep: sleepContext,
+	}, nil
+}
+
+// Returns a replayer reading the recording file at path, Close closes it
+func OpenReplayer(path string, speed float64) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewReplayer(f, speed)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	p.closer = f
+	return p, nil
+}
+
+// Replays every change message of the recording, onMarket and onOrder are
+// called from the calling goroutine and either may be nil. Feeding them to
+// a MarketCache and an OrderCache rebuilds the recorded state:
+//
+//	err := p.Replay(ctx, func(m *betfair.MarketChangeMessage) {
+//		markets.Apply(m)
+//	}, func(m *betfair.OrderChangeMessage) {
+//		orders.Apply(m)
+//	})
+func (p *Replayer) Replay(ctx context.Context,
+	onMarket func(*MarketChangeMessage),
+	onOrder func(*OrderChangeMessage)) error {
+	var (
+		segments   segmenter
+		heartbeats heartbeatFilter
+		start      time.Time // first receive time
+		replayFrom time.Time
+	)
+
+	for n := 1; ; n++ {
+		t, line, err := p.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("replayer: record %d: %w", n, err)
+		}
+
+		if p.speed > 0 {
+			if start.IsZero() {
+				start, replayFrom = t, time.Now()
+			}
+			due := time.Duration(float64(t.Sub(start)) / p.speed)
+			if err := p.sleep(ctx, due-time.Since(replayFrom)); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var head struct{ Op string }
+		if err := json.Unmarshal(line, &head); err != nil {
+			return fmt.Errorf("replayer: record %d: %w", n, err)
+		}
+
+		switch head.Op {
+		case "mcm":
+			var msg MarketChangeMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				return fmt.Errorf("replayer: record %d: %w", n, err)
+			}
+			m := segments.joinMarket(&msg)
+			if m != nil && heartbeats.deliver(m) && onMarket != nil {
+				onMarket(m)
+			}
+		case "ocm":
+			var msg OrderChangeMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				return fmt.Errorf("replayer: record %d: %w", n, err)
+			}
+			o := segments.joinOrder(&msg)
+			if o != nil && o.Ct != ChangeTypeHeartbeat && onOrder != nil {
+				onOrder(o)
+			}
+		}
+	}
+}
+
+// Closes the recording and the file of OpenReplayer
+func (p *Replayer) Close() error {
+	err := p.gz.Close()
+	if p.closer != nil {
+		if cerr := p.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// returns the next record
+func (p *Replayer) next() (time.Time, []byte, error) {
+	line, err := p.r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	i := bytes.IndexByte(line, '\t')
+	if i < 0 {
+		return time.Time{}, nil, errors.New("malformed record")
+	}
+	nanos, err := strconv.ParseInt(string(line[:i]), 10, 64)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("malformed record time: %w", err)
+	}
+	return time.Unix(0, nanos), bytes.TrimRight(line[i+1:], "\n"), nil
+}
+
+// waits d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,186 @@
+package betfair
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_RecorderReplayer(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf)
+	start := time.Unix(1500000000, 0)
+	for i, line := range []string{
+		`{"op":"connection","connectionId":"conn-1"}`,
+		`{"op":"mcm","ct":"SUB_IMAGE","segmentType":"SEG_START","mc":[` +
+			`{"id":"1.2","img":true,"rc":[{"id":10,"atb":[[2.5,10]]}]}]}`,
+		`{"op":"mcm","segmentType":"SEG_END","clk":"c1","mc":[` +
+			`{"id":"1.3","img":true}]}`,
+		`{"op":"mcm","ct":"HEARTBEAT","clk":"c2"}`,
+		`{"op":"ocm","oc":[{"id":"1.2","orc":[{"id":10,"uo":[{"id":"b1",` +
+			`"p":2.5,"s":10,"side":"B","status":"E","sr":10}]}]}]}`,
+		`{"op":"mcm","clk":"c3","mc":[{"id":"1.2","rc":[{"id":10,` +
+			`"atb":[[2.5,0],[2.6,3]]}]}]}`,
+	} {
+		if err := rec.Record(start.Add(time.Duration(i)*time.Second),
+			[]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	recording := buf.Bytes()
+
+	p, err := NewReplayer(bytes.NewReader(recording), ReplayAsFastAsPossible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markets, orders := NewMarketCache(), NewOrderCache()
+	var changes int
+	err = p.Replay(context.Background(), func(m *MarketChangeMessage) {
+		changes++
+		markets.Apply(m)
+	}, func(m *OrderChangeMessage) {
+		orders.Apply(m)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes != 2 {
+		t.Error("segments not joined or heartbeat delivered", changes)
+	}
+	snap, _ := markets.Snapshot("1.2")
+	if len(snap.Runners) != 1 ||
+		snap.Runners[0].Ex.AvailableToBack[0] != (PriceSize{2.6, 3}) {
+		t.Error("market state not replayed", snap.Runners)
+	}
+	if _, ok := markets.Snapshot("1.3"); !ok {
+		t.Error("second segment not replayed")
+	}
+	if _, ok := orders.Order("1.2", 10, "b1"); !ok {
+		t.Error("order not replayed")
+	}
+
+	// accelerated replay waits a tenth of the recorded gaps
+	p, _ = NewReplayer(bytes.NewReader(recording), 10)
+	var waited []time.Duration
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		waited = append(waited, d)
+		return nil
+	}
+	if err := p.Replay(context.Background(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	last := waited[len(waited)-1]
+	if len(waited) != 6 || last > 500*time.Millisecond ||
+		last < 400*time.Millisecond {
+		t.Error("replay not accelerated", waited)
+	}
+
+	// a canceled replay stops
+	p, _ = NewReplayer(bytes.NewReader(recording), ReplayRealTime)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if err := p.Replay(ctx, nil, nil); err != context.DeadlineExceeded {
+		t.Error("replay not canceled", err)
+	}
+}
+
+func Test_StreamRecorder(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		sub := c.read()
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"clk":"c1","mc":[{"id":"1.2",`+
+			`"img":true,"tv":7}]}`, sub["id"])
+		c.read()
+	})
+
+	path := filepath.Join(t.TempDir(), "stream.gz")
+	rec, err := CreateRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx, WithStreamRecorder(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-st.MarketChanges():
+	case <-ctx.Done():
+		t.Fatal("no market change received")
+	}
+	st.Close()
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := OpenReplayer(path, ReplayAsFastAsPossible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	markets := NewMarketCache()
+	if err := p.Replay(ctx, func(m *MarketChangeMessage) {
+		markets.Apply(m)
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if snap, ok := markets.Snapshot("1.2"); !ok || snap.TotalMatched != 7 {
+		t.Error("recorded stream not replayed", snap)
+	}
+}
+
+func Test_ReplayerStaleHeartbeats(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf)
+	start := time.Unix(1500000000, 0)
+	for i, line := range []string{
+		`{"op":"mcm","ct":"SUB_IMAGE","clk":"c1","mc":[{"id":"1.2",` +
+			`"img":true}]}`,
+		`{"op":"mcm","ct":"HEARTBEAT","clk":"c2"}`,
+		`{"op":"mcm","ct":"HEARTBEAT","clk":"c3","status":503}`,
+		`{"op":"mcm","ct":"HEARTBEAT","clk":"c4","status":503}`,
+		`{"op":"mcm","ct":"HEARTBEAT","clk":"c5"}`,
+	} {
+		if err := rec.Record(start.Add(time.Duration(i)*time.Second),
+			[]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewReplayer(&buf, ReplayAsFastAsPossible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cache turns stale and fresh again as it did on the stream
+	markets := NewMarketCache()
+	var seen []string
+	err = p.Replay(context.Background(), func(m *MarketChangeMessage) {
+		markets.Apply(m)
+		snap, _ := markets.Snapshot("1.2")
+		seen = append(seen, fmt.Sprintf("%s/%d", m.Clk, snap.StreamStatus))
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(seen, " ") != "c1/0 c3/503 c5/0" {
+		t.Error("unexpected replayed changes", seen)
+	}
+}
@@ -0,0 +1,618 @@
+package betfair
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Request is implemented by the per-operation request types (ListEventsRequest,
+// ListMarketBookRequest, PlaceOrdersRequest...) and, for compatibility, by
+// the deprecated catch-all Query.
+type Request interface {
+	// Validate checks required fields before anything is sent
+	Validate() error
+
+	// operation returns the betting method the request belongs to, empty if
+	// it is accepted by every method
+	operation() string
+
+	// query returns the request as Query for visitor functions
+	query() *Query
+}
+
+// implemented by requests of operations paging with fromRecord/recordCount
+type pagedRequest interface {
+	Request
+	withFromRecord(n int) Request
+}
+
+// maximum records and instructions accepted by a single call
+const (
+	maxMarketCatalogueResults = 1000
+	maxRecordCount            = 1000
+	maxPlaceInstructions      = 200
+	maxOtherInstructions      = 60
+)
+
+func errNilRequest(method string) error {
+	return errors.New(method + ": request parameter can not be nil")
+}
+
+func errRequired(method, field string) error {
+	return fmt.Errorf("%s: %s is required", method, field)
+}
+
+func checkRecordCount(method string, n int) error {
+	if n < 0 || n > maxRecordCount {
+		return fmt.Errorf("%s: record count must be between 0 and %d", method,
+			maxRecordCount)
+	}
+	return nil
+}
+
+func checkInstructionCount(method string, n, max int) error {
+	if n > max {
+		return fmt.Errorf("%s: at most %d instructions are allowed", method, max)
+	}
+	return nil
+}
+
+// checks every place instruction carries the order its order type needs
+func checkPlaceInstructions(method string, in PlaceInstructions) error {
+	for i, p := range in {
+		var ok bool
+		switch p.OrderType {
+		case OrderTypeLimit:
+			ok = p.LimitOrder != nil
+		case OrderTypeLimitOnClose:
+			ok = p.LimitOnCloseOrder != nil
+		case OrderTypeMarketOnClose:
+			ok = p.MarketOnCloseOrder != nil
+		default:
+			return fmt.Errorf("%s: instruction %d has invalid order type %q",
+				method, i, p.OrderType)
+		}
+		if !ok {
+			return fmt.Errorf("%s: instruction %d of order type %s has no "+
+				"matching order", method, i, p.OrderType)
+		}
+	}
+	return nil
+}
+
+// Query is only checked for being set here, operation specific checks are
+// done before sending it.
+func (q *Query) Validate() error {
+	if q == nil {
+		return errors.New("query parameter can not be nil")
+	}
+	return nil
+}
+
+func (q *Query) operation() string { return "" }
+
+func (q *Query) query() *Query { return q }
+
+// operation specific checks of Query
+func (q *Query) check(method string) error {
+	switch method {
+	case "placeOrders", "replaceOrders", "updateOrders":
+		return checkInstructions(q, method, false)
+	case "cancelOrders":
+		return checkInstructions(q, method, true)
+	case "listClearedOrders":
+		if q.BetStatus == "" {
+			return errRequired(method, "bet status")
+		}
+	}
+	return nil
+}
+
+// checks query carries a market id and instructions of given operation,
+// optional lets both be omitted together. A nil or empty instruction list
+// would be sent as null or [] and is rejected.
+func checkInstructions(q *Query, method string, optional bool) error {
+	if optional && q.Instructions == nil {
+		return nil
+	}
+	if q.MarketId == "" {
+		return errRequired(method, "market id")
+	}
+	if q.Instructions == nil || q.Instructions.operation() != method {
+		return errors.New(method + ": invalid instructions")
+	}
+	if q.Instructions.len() == 0 {
+		return errRequired(method, "instructions")
+	}
+	if p, ok := q.Instructions.(PlaceInstructions); ok {
+		if err := checkInstructionCount(method, p.len(),
+			maxPlaceInstructions); err != nil {
+			return err
+		}
+		return checkPlaceInstructions(method, p)
+	}
+	return checkInstructionCount(method, q.Instructions.len(),
+		maxOtherInstructions)
+}
+
+func (q *Query) withFromRecord(n int) Request {
+	page := *q
+	page.FromRecord = n
+	return &page
+}
+
+// Parameters of listEventTypes. Filter is always sent, an empty filter
+// selects every market.
+type ListEventTypesRequest struct {
+	Filter MarketFilter `json:"filter"`
+	Locale string       `json:"locale,omitempty"`
+}
+
+func (r *ListEventTypesRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listEventTypes")
+	}
+	return nil
+}
+
+func (r *ListEventTypesRequest) operation() string { return "listEventTypes" }
+
+func (r *ListEventTypesRequest) query() *Query {
+	return &Query{MarketFilter: &r.Filter, Locale: r.Locale}
+}
+
+// Parameters of listCompetitions. Filter is always sent, an empty filter
+// selects every market.
+type ListCompetitionsRequest struct {
+	Filter MarketFilter `json:"filter"`
+	Locale string       `json:"locale,omitempty"`
+}
+
+func (r *ListCompetitionsRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listCompetitions")
+	}
+	return nil
+}
+
+func (r *ListCompetitionsRequest) operation() string { return "listCompetitions" }
+
+func (r *ListCompetitionsRequest) query() *Query {
+	return &Query{MarketFilter: &r.Filter, Locale: r.Locale}
+}
+
+// Parameters of listCountries. Filter is always sent, an empty filter
+// selects every market.
+type ListCountriesRequest struct {
+	Filter MarketFilter `json:"filter"`
+	Locale string       `json:"locale,omitempty"`
+}
+
+func (r *ListCountriesRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listCountries")
+	}
+	return nil
+}
+
+func (r *ListCountriesRequest) operation() string { return "listCountries" }
+
+func (r *ListCountriesRequest) query() *Query {
+	return &Query{MarketFilter: &r.Filter, Locale: r.Locale}
+}
+
+// Parameters of listEvents. Filter is always sent, an empty filter selects
+// every market.
+type ListEventsRequest struct {
+	Filter MarketFilter `json:"filter"`
+	Locale string       `json:"locale,omitempty"`
+}
+
+func (r *ListEventsRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listEvents")
+	}
+	return nil
+}
+
+func (r *ListEventsRequest) operation() string { return "listEvents" }
+
+func (r *ListEventsRequest) query() *Query {
+	return &Query{MarketFilter: &r.Filter, Locale: r.Locale}
+}
+
+// Parameters of listMarketTypes. Filter is always sent, an empty filter
+// selects every market.
+type ListMarketTypesRequest struct {
+	Filter MarketFilter `json:"filter"`
+	Locale string       `json:"locale,omitempty"`
+}
+
+func (r *ListMarketTypesRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listMarketTypes")
+	}
+	return nil
+}
+
+func (r *ListMarketTypesRequest) operation() string { return "listMarketTypes" }
+
+func (r *ListMarketTypesRequest) query() *Query {
+	return &Query{MarketFilter: &r.Filter, Locale: r.Locale}
+}
+
+// Parameters of listVenues. Filter is always sent, an empty filter selects
+// every market.
+type ListVenuesRequest struct {
+	Filter MarketFilter `json:"filter"`
+	Locale string       `json:"locale,omitempty"`
+}
+
+func (r *ListVenuesRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listVenues")
+	}
+	return nil
+}
+
+func (r *ListVenuesRequest) operation() string { return "listVenues" }
+
+func (r *ListVenuesRequest) query() *Query {
+	return &Query{MarketFilter: &r.Filter, Locale: r.Locale}
+}
+
+// Parameters of listMarketCatalogue. MaxResults is required (1-1000).
+// Without MarketProjection only market id, name and total matched are
+// returned; Sort defaults to RANK on the server.
+type ListMarketCatalogueRequest struct {
+	Filter           MarketFilter `json:"filter"`
+	MarketProjection []string     `json:"marketProjection,omitempty"`
+	Sort             string       `json:"sort,omitempty"`
+	MaxResults       int          `json:"maxResults"`
+	Locale           string       `json:"locale,omitempty"`
+}
+
+func (r *ListMarketCatalogueRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listMarketCatalogue")
+	}
+	if r.MaxResults < 1 || r.MaxResults > maxMarketCatalogueResults {
+		return fmt.Errorf("listMarketCatalogue: max results must be between 1 "+
+			"and %d", maxMarketCatalogueResults)
+	}
+	return nil
+}
+
+func (r *ListMarketCatalogueRequest) operation() string {
+	return "listMarketCatalogue"
+}
+
+func (r *ListMarketCatalogueRequest) query() *Query {
+	q := &Query{
+		MarketFilter:     &r.Filter,
+		MarketProjection: r.MarketProjection,
+		MaxResults:       uint16(r.MaxResults),
+		Locale:           r.Locale,
+	}
+	if r.Sort != "" {
+		q.MarketSort = []string{r.Sort}
+	}
+	return q
+}
+
+// Parameters of listMarketBook. MarketIds is required. Without
+// PriceProjection no prices are returned, OrderProjection and
+// MatchProjection default to no orders and no matches.
+type ListMarketBookRequest struct {
+	MarketIds            []string         `json:"marketIds"`
+	PriceProjection      *PriceProjection `json:"priceProjection,omitempty"`
+	OrderProjection      string           `json:"orderProjection,omitempty"`
+	MatchProjection      string           `json:"matchProjection,omitempty"`
+	CustomerStrategyRefs []string         `json:"customerStrategyRefs,omitempty"`
+	CurrencyCode         string           `json:"currencyCode,omitempty"`
+	Locale               string           `json:"locale,omitempty"`
+	BetIds               []string         `json:"betIds,omitempty"`
+}
+
+func (r *ListMarketBookRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listMarketBook")
+	}
+	if len(r.MarketIds) == 0 {
+		return errRequired("listMarketBook", "market ids")
+	}
+	return nil
+}
+
+func (r *ListMarketBookRequest) operation() string { return "listMarketBook" }
+
+func (r *ListMarketBookRequest) query() *Query {
+	return &Query{
+		MarketIds:            r.MarketIds,
+		PriceProjection:      r.PriceProjection,
+		OrderProjection:      r.OrderProjection,
+		MatchProjection:      r.MatchProjection,
+		CustomerStrategyRefs: r.CustomerStrategyRefs,
+		CurrencyCode:         r.CurrencyCode,
+		Locale:               r.Locale,
+		BetIds:               r.BetIds,
+	}
+}
+
+// Parameters of listMarketProfitAndLoss. MarketIds is required, settled
+// and BSP bets are excluded and commission is not netted by default.
+type ListMarketProfitAndLossRequest struct {
+	MarketIds          []string `json:"marketIds"`
+	IncludeSettledBets bool     `json:"includeSettledBets,omitempty"`
+	IncludeBspBets     bool     `json:"includeBspBets,omitempty"`
+	NetOfCommission    bool     `json:"netOfCommission,omitempty"`
+}
+
+func (r *ListMarketProfitAndLossRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listMarketProfitAndLoss")
+	}
+	if len(r.MarketIds) == 0 {
+		return errRequired("listMarketProfitAndLoss", "market ids")
+	}
+	return nil
+}
+
+func (r *ListMarketProfitAndLossRequest) operation() string {
+	return "listMarketProfitAndLoss"
+}
+
+func (r *ListMarketProfitAndLossRequest) query() *Query {
+	return &Query{
+		MarketIds:          r.MarketIds,
+		IncludeSettledBets: r.IncludeSettledBets,
+		IncludeBspBets:     r.IncludeBspBets,
+		NetOfCommission:    r.NetOfCommission,
+	}
+}
+
+// Parameters of placeOrders. MarketId and 1-200 Instructions are required,
+// each with the order of its OrderType (e.g. LimitOrder for LIMIT). Orders
+// are placed synchronously unless Async is set.
+type PlaceOrdersRequest struct {
+	MarketId            string            `json:"marketId"`
+	Instructions        PlaceInstructions `json:"instructions"`
+	CustomerRef         string            `json:"customerRef,omitempty"`
+	MarketVersion       *MarketVersion    `json:"marketVersion,omitempty"`
+	CustomerStrategyRef string            `json:"customerStrategyRef,omitempty"`
+	Async               bool              `json:"async,omitempty"`
+}
+
+func (r *PlaceOrdersRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("placeOrders")
+	}
+	if r.MarketId == "" {
+		return errRequired("placeOrders", "market id")
+	}
+	if len(r.Instructions) == 0 {
+		return errRequired("placeOrders", "instructions")
+	}
+	if err := checkInstructionCount("placeOrders", len(r.Instructions),
+		maxPlaceInstructions); err != nil {
+		return err
+	}
+	return checkPlaceInstructions("placeOrders", r.Instructions)
+}
+
+func (r *PlaceOrdersRequest) operation() string { return "placeOrders" }
+
+func (r *PlaceOrdersRequest) query() *Query {
+	return &Query{
+		MarketId:            r.MarketId,
+		Instructions:        r.Instructions,
+		CustomerRef:         r.CustomerRef,
+		MarketVersion:       r.MarketVersion,
+		CustomerStrategyRef: r.CustomerStrategyRef,
+		Async:               r.Async,
+	}
+}
+
+// Parameters of cancelOrders. Instructions (at most 60) require MarketId;
+// without instructions every bet on MarketId, or on every market if it is
+// empty, is cancelled.
+type CancelOrdersRequest struct {
+	MarketId     string             `json:"marketId,omitempty"`
+	Instructions CancelInstructions `json:"instructions,omitempty"`
+	CustomerRef  string             `json:"customerRef,omitempty"`
+}
+
+func (r *CancelOrdersRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("cancelOrders")
+	}
+	if len(r.Instructions) > 0 && r.MarketId == "" {
+		return errRequired("cancelOrders", "market id")
+	}
+	return checkInstructionCount("cancelOrders", len(r.Instructions),
+		maxOtherInstructions)
+}
+
+func (r *CancelOrdersRequest) operation() string { return "cancelOrders" }
+
+func (r *CancelOrdersRequest) query() *Query {
+	q := &Query{MarketId: r.MarketId, CustomerRef: r.CustomerRef}
+	if len(r.Instructions) > 0 {
+		q.Instructions = r.Instructions
+	}
+	return q
+}
+
+// Parameters of replaceOrders. MarketId and 1-60 Instructions are required.
+type ReplaceOrdersRequest struct {
+	MarketId      string              `json:"marketId"`
+	Instructions  ReplaceInstructions `json:"instructions"`
+	CustomerRef   string              `json:"customerRef,omitempty"`
+	MarketVersion *MarketVersion      `json:"marketVersion,omitempty"`
+	Async         bool                `json:"async,omitempty"`
+}
+
+func (r *ReplaceOrdersRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("replaceOrders")
+	}
+	if r.MarketId == "" {
+		return errRequired("replaceOrders", "market id")
+	}
+	if len(r.Instructions) == 0 {
+		return errRequired("replaceOrders", "instructions")
+	}
+	return checkInstructionCount("replaceOrders", len(r.Instructions),
+		maxOtherInstructions)
+}
+
+func (r *ReplaceOrdersRequest) operation() string { return "replaceOrders" }
+
+func (r *ReplaceOrdersRequest) query() *Query {
+	return &Query{
+		MarketId:      r.MarketId,
+		Instructions:  r.Instructions,
+		CustomerRef:   r.CustomerRef,
+		MarketVersion: r.MarketVersion,
+		Async:         r.Async,
+	}
+}
+
+// Parameters of updateOrders. MarketId and 1-60 Instructions are required.
+type UpdateOrdersRequest struct {
+	MarketId     string             `json:"marketId"`
+	Instructions UpdateInstructions `json:"instructions"`
+	CustomerRef  string             `json:"customerRef,omitempty"`
+}
+
+func (r *UpdateOrdersRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("updateOrders")
+	}
+	if r.MarketId == "" {
+		return errRequired("updateOrders", "market id")
+	}
+	if len(r.Instructions) == 0 {
+		return errRequired("updateOrders", "instructions")
+	}
+	return checkInstructionCount("updateOrders", len(r.Instructions),
+		maxOtherInstructions)
+}
+
+func (r *UpdateOrdersRequest) operation() string { return "updateOrders" }
+
+func (r *UpdateOrdersRequest) query() *Query {
+	return &Query{
+		MarketId:     r.MarketId,
+		Instructions: r.Instructions,
+		CustomerRef:  r.CustomerRef,
+	}
+}
+
+// Parameters of listCurrentOrders. Every filter is optional; OrderBy
+// defaults to BY_BET, SortDir to EARLIEST_TO_LATEST and RecordCount (at
+// most 1000) to the server maximum.
+type ListCurrentOrdersRequest struct {
+	BetIds               []string   `json:"betIds,omitempty"`
+	MarketIds            []string   `json:"marketIds,omitempty"`
+	OrderProjection      string     `json:"orderProjection,omitempty"`
+	CustomerOrderRefs    []string   `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs []string   `json:"customerStrategyRefs,omitempty"`
+	DateRange            *TimeRange `json:"dateRange,omitempty"`
+	OrderBy              string     `json:"orderBy,omitempty"`
+	SortDir              string     `json:"sortDir,omitempty"`
+	FromRecord           int        `json:"fromRecord,omitempty"`
+	RecordCount          int        `json:"recordCount,omitempty"`
+}
+
+func (r *ListCurrentOrdersRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listCurrentOrders")
+	}
+	return checkRecordCount("listCurrentOrders", r.RecordCount)
+}
+
+func (r *ListCurrentOrdersRequest) operation() string {
+	return "listCurrentOrders"
+}
+
+func (r *ListCurrentOrdersRequest) query() *Query {
+	return &Query{
+		BetIds:               r.BetIds,
+		MarketIds:            r.MarketIds,
+		OrderProjection:      r.OrderProjection,
+		CustomerOrderRefs:    r.CustomerOrderRefs,
+		CustomerStrategyRefs: r.CustomerStrategyRefs,
+		DateRange:            r.DateRange,
+		OrderBy:              r.OrderBy,
+		SortDir:              r.SortDir,
+		FromRecord:           r.FromRecord,
+		RecordCount:          r.RecordCount,
+	}
+}
+
+func (r *ListCurrentOrdersRequest) withFromRecord(n int) Request {
+	page := *r
+	page.FromRecord = n
+	return &page
+}
+
+// Parameters of listClearedOrders. BetStatus is required; GroupBy defaults
+// to BET and RecordCount (at most 1000) to the server maximum.
+type ListClearedOrdersRequest struct {
+	BetStatus              string     `json:"betStatus"`
+	EventTypeIds           []string   `json:"eventTypeIds,omitempty"`
+	EventIds               []string   `json:"eventIds,omitempty"`
+	MarketIds              []string   `json:"marketIds,omitempty"`
+	RunnerIds              []RunnerId `json:"runnerIds,omitempty"`
+	BetIds                 []string   `json:"betIds,omitempty"`
+	CustomerOrderRefs      []string   `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs   []string   `json:"customerStrategyRefs,omitempty"`
+	Side                   string     `json:"side,omitempty"`
+	SettledDateRange       *TimeRange `json:"settledDateRange,omitempty"`
+	GroupBy                string     `json:"groupBy,omitempty"`
+	IncludeItemDescription bool       `json:"includeItemDescription,omitempty"`
+	Locale                 string     `json:"locale,omitempty"`
+	FromRecord             int        `json:"fromRecord,omitempty"`
+	RecordCount            int        `json:"recordCount,omitempty"`
+}
+
+func (r *ListClearedOrdersRequest) Validate() error {
+	if r == nil {
+		return errNilRequest("listClearedOrders")
+	}
+	if r.BetStatus == "" {
+		return errRequired("listClearedOrders", "bet status")
+	}
+	return checkRecordCount("listClearedOrders", r.RecordCount)
+}
+
+func (r *ListClearedOrdersRequest) operation() string {
+	return "listClearedOrders"
+}
+
+func (r *ListClearedOrdersRequest) query() *Query {
+	return &Query{
+		BetStatus:              r.BetStatus,
+		EventTypeIds:           r.EventTypeIds,
+		EventIds:               r.EventIds,
+		MarketIds:              r.MarketIds,
+		RunnerIds:              r.RunnerIds,
+		BetIds:                 r.BetIds,
+		CustomerOrderRefs:      r.CustomerOrderRefs,
+		CustomerStrategyRefs:   r.CustomerStrategyRefs,
+		Side:                   r.Side,
+		SettledDateRange:       r.SettledDateRange,
+		GroupBy:                r.GroupBy,
+		IncludeItemDescription: r.IncludeItemDescription,
+		Locale:                 r.Locale,
+		FromRecord:             r.FromRecord,
+		RecordCount:            r.RecordCount,
+	}
+}
+
+func (r *ListClearedOrdersRequest) withFromRecord(n int) Request {
+	page := *r
+	page.FromRecord = n
+	return &page
+}
@@ -0,0 +1,56 @@
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func Test_RequestValidate(t *testing.T) {
+	if err := (&ListMarketBookRequest{}).Validate(); err == nil {
+		t.Error("missing market ids not reported")
+	}
+
+	if err := (&ListMarketCatalogueRequest{}).Validate(); err == nil {
+		t.Error("missing max results not reported")
+	}
+
+	if err := (&ListClearedOrdersRequest{}).Validate(); err == nil {
+		t.Error("missing bet status not reported")
+	}
+
+	if err := (&CancelOrdersRequest{}).Validate(); err != nil {
+		t.Error("cancel all orders rejected", err)
+	}
+
+	var q *Query
+	if err := q.Validate(); err == nil {
+		t.Error("nil query not reported")
+	}
+}
+
+func Test_betRequestOperation(t *testing.T) {
+	s := &Session{}
+	r := &ListMarketBookRequest{MarketIds: []string{"1.1"}}
+	if err := betRequest(context.Background(), "listEvents", s, r, nil); err == nil {
+		t.Error("request of another operation accepted")
+	}
+
+	if err := betRequest(context.Background(), "placeOrders", s, &Query{MarketId: "1.1"}, nil); err == nil {
+		t.Error("query without instructions accepted")
+	}
+}
+
+func Test_RequestMarshal(t *testing.T) {
+	p, err := json.Marshal(&ListMarketCatalogueRequest{
+		MaxResults: 10,
+		Sort:       "FIRST_TO_START",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(p) != `{"filter":{},"sort":"FIRST_TO_START","maxResults":10}` {
+		t.Error("unexpected payload", string(p))
+	}
+}
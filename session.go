@@ -1,300 +1,244 @@
 package betfair
 
 import (
+	"context"
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 )
 
-var endpoints = map[string]map[string]string{
-	"UK": map[string]string{
-		"certLogin": "https://identitysso-api.betfair.com/api/certlogin",
-		"restLogin": "https://identitysso.betfair.com/api/login",
-		"betting":   "https://api.betfair.com/exchange/betting/rest/v1.0/",
-		"account":   "https://api.betfair.com/exchange/account/rest/v1.0/",
-	},
-	"AU": map[string]string{
-		"certLogin": "https://identitysso-api.betfair.com/api/certlogin",
-		"restLogin": "https://identitysso.betfair.com/api/login",
-		"betting":   "https://api-au.betfair.com/exchange/betting/rest/v1.0/",
-		"account":   "https://api-au.betfair.com/exchange/account/rest/v1.0/",
-	},
-}
-
-// NewCredentials func ret val
-type CredentialInterface interface{}
-
-// inherited user credentials type
-type userCredentials struct {
-	Username string
-	Password string
-	Exchange string
-}
-
-// Used for interactive login and request payload and headers
-type InteractiveCredentials struct {
-	*userCredentials
-	ApplicationKey string
-}
-
-// Used for NonInteractive (cert-login)
-type NonInteractiveCredentials struct {
-	*userCredentials
-	CertPath struct {
-		CrtFile string
-		KeyFile string
-	}
-}
-
 type Session struct {
-	token              string
-	credentials        CredentialInterface
-	requestCredentials *InteractiveCredentials
-	httpClient         *http.Client
-	logger             *log.Logger
-	developerApps      *[]developerApp
+	mu            sync.RWMutex // guards token, appKey, developerApps, keepAlive
+	token         string
+	loginMu       sync.Mutex // serializes re-login
+	credentials   Credentials
+	appKey        string // guarded by mu
+	httpClient    *http.Client
+	loginClient   *http.Client // carries the client certificate
+	endpoints     *Endpoints
+	logger        Logger         // guarded by mu
+	developerApps []DeveloperApp // guarded by mu, nil until fetched
+	keepAlive     *keepAliveLoop
 }
 
-// returns CredentialInterface
-// NewCredentials(username, password, exchange, appkey string) InteractiveCredentials
-// NewCredentials(username, password, exchange, crtfile, keyfile) NonInteractiveCredentials
-func NewCredentials(params ...string) (CredentialInterface, error) {
-	lp := len(params)
-	if lp != 4 && lp != 5 {
-		return nil, errors.New("invalid credential params")
-	}
-
-	var c CredentialInterface
-
-	if lp == 5 {
-		// assign NonInteractiveCredential pointer
-		c = &NonInteractiveCredentials{
-			userCredentials: &userCredentials{
-				Username: params[0],
-				Password: params[1],
-				Exchange: params[2],
-			},
-			CertPath: struct {
-				CrtFile string
-				KeyFile string
-			}{
-				CrtFile: params[3],
-				KeyFile: params[4],
-			},
-		}
-		return c, nil
+// returns the http.Client used for logging in with credentials, base is
+// copied if set so the caller's client is never modified. Credentials with a
+// client certificate get a client attaching it to a clone of base transport,
+// it is only used for login.
+func getHttpClient(credentials Credentials, base *http.Client) (
+	*http.Client, error) {
+	client := &http.Client{}
+	if base != nil {
+		*client = *base
 	}
 
-	// if params len is 3 then
-	// assign InteractiveCredentials pointer
-	c = &InteractiveCredentials{
-		userCredentials: &userCredentials{
-			Username: params[0],
-			Password: params[1],
-			Exchange: params[2],
-		},
-		ApplicationKey: params[3],
+	cert, err := credentials.ClientCertificate()
+	if err != nil {
+		return nil, err
 	}
-	return c, nil
-}
-
-// returns http.Client according to credentials
-func getHttpClient(credentials CredentialInterface) (*http.Client, error) {
-	client := &http.Client{}
 
-	if c, ok := credentials.(*NonInteractiveCredentials); ok {
-		// check crt and key file exists
-		if _, err := os.Stat(c.CertPath.CrtFile); os.IsNotExist(err) {
-			return nil, err
-		}
-
-		if _, err := os.Stat(c.CertPath.KeyFile); os.IsNotExist(err) {
-			return nil, err
-		}
-
-		// load key
-		cert, err := tls.LoadX509KeyPair(c.CertPath.CrtFile, c.CertPath.KeyFile)
+	if cert != nil {
+		// set client transport
+		transport, err := cloneTransport(client.Transport)
 		if err != nil {
 			return nil, err
 		}
-
-		// set client transport
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				Certificates:       []tls.Certificate{cert},
-				InsecureSkipVerify: true,
-			},
-		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		client.Transport = transport
 	}
 
 	return client, nil
 }
 
-// returns Session struct
-func NewSession(credentials CredentialInterface, out ...io.Writer) (*Session,
-	error) {
-	session := &Session{
-		credentials: credentials,
+// clones t to change its TLS settings, only *http.Transport (or nil for the
+// default transport) can be configured
+func cloneTransport(t http.RoundTripper) (*http.Transport, error) {
+	var transport *http.Transport
+	switch v := t.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = v.Clone()
+	default:
+		return nil, fmt.Errorf("TLS settings need *http.Transport, got %T", t)
 	}
 
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	return transport, nil
+}
+
+// returns Session struct, same as New with WithLogger if out is given
+func NewSession(credentials Credentials, out ...io.Writer) (*Session,
+	error) {
+	return NewSessionContext(context.Background(), credentials, out...)
+}
+
+// Like NewSession, ctx controls the login request
+func NewSessionContext(ctx context.Context, credentials Credentials,
+	out ...io.Writer) (*Session, error) {
 	// set logger if provided
-	var logof io.Writer
+	var opts []Option
 	if len(out) > 0 {
-		logof = out[0]
-	} else {
-		logof = os.Stderr
+		opts = append(opts, WithLogger(newDefaultLogger(out[0])))
 	}
-	session.logger = log.New(logof, PKG_NAME+" ", log.LstdFlags|log.Lshortfile)
 
-	// set client
-	client, err := getHttpClient(session.credentials)
+	return New(ctx, credentials, opts...)
+}
+
+// logs in with session credentials and stores the new token
+func (s *Session) login(ctx context.Context) error {
+	url := s.credentials.LoginEndpoint(s.endpoints)
+	req, err := s.credentials.NewLoginRequest(ctx, url)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	session.httpClient = client
-	// restruct credentials
-	setRequestCredentials(session)
-
-	// LOGIN OPS
-	payload := strings.NewReader(
-		fmt.Sprintf("username=%s&password=%s",
-			session.requestCredentials.Username,
-			session.requestCredentials.Password))
-
-	var endpoint string = "restLogin"
-	if _, ok := session.credentials.(*NonInteractiveCredentials); ok {
-		endpoint = "certLogin"
+
+	resp, err := send(s, s.loginClient, req, "login")
+	if err != nil {
+		return err
 	}
 
-	resp, err := doRequest(session, endpoint, "", payload)
+	token, err := s.credentials.ParseLoginResponse(resp)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	s.setToken(token)
 
-	switch session.credentials.(type) {
-	case *InteractiveCredentials:
-		var result struct {
-			Token   string
-			Product string
-			Status  string
-			Error   string
-		}
+	return nil
+}
 
-		if err := json.Unmarshal(resp, &result); err != nil {
-			return nil, err
-		}
+// returns current session token
+func (s *Session) sessionToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token
+}
 
-		if result.Status != "SUCCESS" {
-			return nil, errors.New(result.Error)
-		}
-		session.token = result.Token
-	case *NonInteractiveCredentials:
-		var result struct {
-			Token  string `json:"sessionToken"`
-			Status string `json:"loginStatus"`
-		}
+func (s *Session) setToken(token string) {
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+}
 
-		if err := json.Unmarshal(resp, &result); err != nil {
-			return nil, err
-		}
+// returns application key sent as X-Application header
+func (s *Session) applicationKey() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.appKey
+}
 
-		if result.Status != "SUCCESS" {
-			return nil, errors.New(result.Status)
-		}
-		session.token = result.Token
+func (s *Session) setApplicationKey(key string) {
+	s.mu.Lock()
+	s.appKey = key
+	s.mu.Unlock()
+}
+
+// Replaces the session logger, nil discards every record. It is safe to
+// call while requests, keep-alive or streams are running.
+func (s *Session) SetLogger(l Logger) {
+	if l == nil {
+		l = NewNopLogger()
 	}
+	s.mu.Lock()
+	s.logger = l
+	s.mu.Unlock()
+}
 
-	return session, nil
+// returns the session logger
+func (s *Session) log() Logger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.logger
 }
 
-// restruct credentials for requests
-func setRequestCredentials(s *Session) {
-	if c, ok := s.credentials.(*InteractiveCredentials); ok {
-		s.requestCredentials = c
-	} else {
-		c := s.credentials.(*NonInteractiveCredentials)
-		s.requestCredentials = &InteractiveCredentials{
-			userCredentials: c.userCredentials,
-			ApplicationKey:  "",
-		}
-	}
+// reports whether endpoint is called with the session token and may be
+// retried after a re-login
+func isAPIEndpoint(endpoint string) bool {
+	return endpoint == "betting" || endpoint == "account"
 }
 
-// prepares betfair endpoints with exchange and method values
-func prepareEndpoint(endpoint, method, exchange string) (string, error) {
-	var url string
-	if _, exists := endpoints[exchange][endpoint]; !exists {
-		return "", errors.New(
-			fmt.Sprintf("invalid endpoint params: %s %s %s", endpoint, method,
-				exchange))
-	} else {
-		url = endpoints[exchange][endpoint]
+// performs request jobs, a betting or account request failing because the
+// session expired is sent once more after logging in again
+func doRequest(ctx context.Context, s *Session, endpoint, method string,
+	body *strings.Reader) ([]byte, error) {
+	token := s.sessionToken()
+	data, err := sendRequest(ctx, s, endpoint, method, token, body)
+	if err == nil || !isAPIEndpoint(endpoint) ||
+		!(errors.Is(err, ErrInvalidSessionInformation) ||
+			errors.Is(err, ErrNoSession)) {
+		return data, err
 	}
 
-	if endpoint != "certLogin" && endpoint != "restLogin" {
-		url += method + "/"
+	s.log().Info("session expired, logging in again", "method", method)
+	if lerr := s.relogin(ctx, token); lerr != nil {
+		return nil, fmt.Errorf("%w (re-login failed: %v)", err, lerr)
 	}
-	return url, nil
+
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return sendRequest(ctx, s, endpoint, method, s.sessionToken(), body)
 }
 
-// performs request jobs
-func doRequest(s *Session, endpoint, method string, body *strings.Reader) (
-	[]byte, error) {
+// sends a single request with given session token
+func sendRequest(ctx context.Context, s *Session, endpoint, method,
+	token string, body *strings.Reader) ([]byte, error) {
 
 	// get completed url
-	url, err := prepareEndpoint(endpoint, method, s.requestCredentials.Exchange)
+	url, err := prepareEndpoint(s.endpoints, endpoint, method)
 	if err != nil {
 		return nil, err
 	}
 
 	// prepare request
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
 
 	// setting X-Application header
-	var xapph string = s.requestCredentials.ApplicationKey
-	if endpoint == "certLogin" {
-		xapph = PKG_NAME
-	}
-	
-	req.Header.Set("X-Application", xapph)
-	if method == "getDeveloperAppKeys" {
-		req.Header.Del("X-Application")
+	if method != "getDeveloperAppKeys" && method != "createDeveloperAppKeys" {
+		req.Header.Set("X-Application", s.applicationKey())
 	}
+	req.Header.Set("X-Authentication", token)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 
-	if endpoint != "certLogin" && endpoint != "restLogin" {
-		req.Header.Set("X-Authentication", s.token)
-	} else {
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	name := method
+	if name == "" {
+		name = endpoint
 	}
+	return send(s, s.httpClient, req, name)
+}
 
-	res, err := s.httpClient.Do(req)
+// sends req with client and returns the response body, non-200 responses
+// are returned as APIError
+func send(s *Session, client *http.Client, req *http.Request, name string) (
+	[]byte, error) {
+	res, err := client.Do(req)
 	if err != nil {
-		return nil, err
-	}
-
-	s.logger.Println(res.Status, url, req.Header, "body:", body, "\n")
-	if res.StatusCode != 200 {
-		return nil, errors.New(res.Status)
+		return nil, fmt.Errorf("%s: %w", name, err)
 	}
 
 	// defer closing body reader
 	defer res.Body.Close()
 
+	s.log().Debug("request", "method", name, "url", req.URL.String(),
+		"status", res.Status)
 	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: reading response: %w", name, err)
+	}
+
+	if res.StatusCode != 200 {
+		return nil, newAPIError(name, res.StatusCode, res.Status, data)
 	}
 
 	return data, nil
@@ -1,7 +1,24 @@
 package betfair
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func Test_NewCredentials(t *testing.T) {
@@ -32,11 +49,16 @@ func Test_NewCredentials(t *testing.T) {
 	if err == nil {
 		t.Fatal("not returned error")
 	}
+
+	c, err = NewCredentials("userName", "passWord", "XX", "appKey")
+	if err == nil {
+		t.Fatal("unknown exchange not returned error")
+	}
 }
 
 func Test_getHttpClient(t *testing.T) {
 	c, _ := NewCredentials("username", "pass", "UK", "appKey")
-	client, err := getHttpClient(c)
+	client, err := getHttpClient(c, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -44,30 +66,232 @@ func Test_getHttpClient(t *testing.T) {
 	if client.Transport != nil {
 		t.Error("client error")
 	}
-	
-	v, _ := NewCredentials("username", "pass", "UK",
-		"/home/baris/whore/betfair-certs/client-2048.crt",
-		"/home/baris/whore/betfair-certs/client-2048.key")
-	client, err = getHttpClient(v)
+
+	crt, key := writeTestCert(t)
+	v, _ := NewCredentials("username", "pass", "UK", crt, key)
+	client, err = getHttpClient(v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := client.Transport.(*http.Transport).TLSClientConfig
+	if len(tc.Certificates) != 1 || tc.InsecureSkipVerify {
+		t.Error("certificate client error")
+	}
+
+	// certificate is only attached to the login client
+	s, err := New(context.Background(), v, WithoutLogin(),
+		WithRootCAs(x509.NewCertPool()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc = s.httpClient.Transport.(*http.Transport).TLSClientConfig
+	if len(tc.Certificates) != 0 || tc.RootCAs == nil {
+		t.Error("api client error")
+	}
+	tc = s.loginClient.Transport.(*http.Transport).TLSClientConfig
+	if len(tc.Certificates) != 1 || tc.RootCAs == nil {
+		t.Error("login client error")
+	}
+}
+
+// writes a self-signed certificate and its key, returns their paths
+func writeTestCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "betfair-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey,
+		key)
 	if err != nil {
 		t.Fatal(err)
 	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	crt, kf := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	err = os.WriteFile(crt, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE",
+		Bytes: der}), 0600)
+	if err == nil {
+		err = os.WriteFile(kf, pem.EncodeToMemory(&pem.Block{
+			Type: "EC PRIVATE KEY", Bytes: kder}), 0600)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crt, kf
 }
 
 func Test_prepareEndpoint(t *testing.T) {
-	url, err := prepareEndpoint("betting", "listEvents", "UK")
+	e, err := ExchangeEndpoints("UK")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if url != "https://api.betfair.com/exchange/betting/rest/v1.0/listEvents" {
+	url, err := prepareEndpoint(&e, "betting", "listEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if url != "https://api.betfair.com/exchange/betting/rest/v1.0/listEvents/" {
 		t.Error("preparing url wrong")
 	}
+
+	e.Betting = "http://127.0.0.1:8080/betting/"
+	url, err = prepareEndpoint(&e, "betting", "listEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if url != "http://127.0.0.1:8080/betting/listEvents/" {
+		t.Error("custom endpoint ignored")
+	}
+
+	if _, err := ExchangeEndpoints("XX"); err == nil {
+		t.Error("unknown exchange accepted")
+	}
+}
+
+// stand-in for the Betfair endpoints, the nth login answers token-n and the
+// test registers other handlers on mux
+type stubServer struct {
+	*httptest.Server
+	mux    *http.ServeMux
+	logins atomic.Int32
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	srv := &stubServer{mux: http.NewServeMux()}
+	srv.mux.HandleFunc("/api/login", func(w http.ResponseWriter,
+		r *http.Request) {
+		if r.FormValue("username") != "userName" {
+			t.Error("username not sent")
+		}
+		n := srv.logins.Add(1)
+		fmt.Fprintf(w, `{"token":"token-%d","status":"SUCCESS"}`, n)
+	})
+	srv.Server = httptest.NewServer(srv.mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (srv *stubServer) endpoints() Endpoints {
+	return Endpoints{
+		Identity: srv.URL + "/api/",
+		Betting:  srv.URL + "/betting/",
+		Account:  srv.URL + "/account/",
+	}
+}
+
+// stub handlers by method name, each gets the decoded request params
+type testMethods map[string]func(params map[string]interface{}) string
+
+// serves the given methods under prefix, e.g. "/betting/"
+func (srv *stubServer) handleMethods(t *testing.T, prefix string,
+	methods testMethods) {
+	for method, fn := range methods {
+		fn := fn
+		srv.mux.HandleFunc(prefix+method+"/", func(w http.ResponseWriter,
+			r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			params := map[string]interface{}{}
+			if err := json.Unmarshal(body, &params); err != nil {
+				t.Errorf("invalid request body %q", body)
+			}
+			fmt.Fprint(w, fn(params))
+		})
+	}
+}
+
+// logged in session against srv
+func (srv *stubServer) session(t *testing.T) *Session {
+	c, _ := NewInteractiveCredentials("userName", "passWord", "UK", "appKey")
+	s, err := New(context.Background(), c, WithLogger(nil),
+		WithEndpoints(srv.endpoints()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// logged in session against a stub serving the given methods under prefix
+func newMethodTestServer(t *testing.T, prefix string,
+	methods testMethods) *Session {
+	srv := newStubServer(t)
+	srv.handleMethods(t, prefix, methods)
+	return srv.session(t)
+}
+
+// serves identity and betting endpoints, betting calls with the expired
+// token fail
+func newTestServer(t *testing.T, expired string) *stubServer {
+	srv := newStubServer(t)
+	srv.mux.HandleFunc("/betting/listEvents/", func(w http.ResponseWriter,
+		r *http.Request) {
+		if r.Header.Get("X-Authentication") == expired {
+			w.WriteHeader(400)
+			fmt.Fprint(w, `{"detail":{"APINGException":`+
+				`{"errorCode":"INVALID_SESSION_INFORMATION"}}}`)
+			return
+		}
+		fmt.Fprint(w, `[{"event":{"id":"1","name":"A v B"},"marketCount":2}]`)
+	})
+	return srv
 }
 
-/*
 func Test_NewSession(t *testing.T) {
-	
+	srv := newTestServer(t, "token-1")
+
+	c, _ := NewCredentials("userName", "passWord", "UK", "appKey")
+	s, err := New(context.Background(), c, WithEndpoints(srv.endpoints()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.sessionToken() != "token-1" {
+		t.Error("token not set")
+	}
+
+	// expired session is logged in again and the call retried once
+	events, err := s.ListEvents(&ListEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.logins.Load() != 2 || len(events) != 1 ||
+		events[0].Event.Name != "A v B" {
+		t.Error("request not retried after re-login", srv.logins.Load(), events)
+	}
+}
+
+func Test_NewWithoutLogin(t *testing.T) {
+	srv := newTestServer(t, "")
+
+	c, _ := NewCredentials("userName", "passWord", "UK", "appKey")
+	s, err := New(context.Background(), c,
+		WithEndpoints(srv.endpoints()),
+		WithHTTPClient(srv.Client()),
+		WithLogger(nil),
+		WithoutLogin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.logins.Load() != 0 {
+		t.Error("logged in on construction")
 	}
-func Test_doRequest(t *testing.T)  {}
-*/
\ No newline at end of file
+
+	// first request logs in
+	if _, err := s.ListEvents(&ListEventsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.logins.Load() != 1 {
+		t.Error("not logged in lazily")
+	}
+}
@@ -0,0 +1,1325 @@
+package betfair
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Market data fields of a market subscription
+const (
+	FieldExBestOffersDisp = "EX_BEST_OFFERS_DISP"
+	FieldExBestOffers     = "EX_BEST_OFFERS"
+	FieldExAllOffers      = "EX_ALL_OFFERS"
+	FieldExTraded         = "EX_TRADED"
+	FieldExTradedVol      = "EX_TRADED_VOL"
+	FieldExLTP            = "EX_LTP"
+	FieldExMarketDef      = "EX_MARKET_DEF"
+	FieldSPTraded         = "SP_TRADED"
+	FieldSPProjected      = "SP_PROJECTED"
+)
+
+// Change types of change messages
+const (
+	ChangeTypeSubImage   = "SUB_IMAGE"
+	ChangeTypeResubDelta = "RESUB_DELTA"
+	ChangeTypeHeartbeat  = "HEARTBEAT"
+)
+
+// Status of change messages while the stream reports stale data, e.g. due to
+// latency upstream. It is zero once the data is up to date again.
+const StreamStatusStale = 503
+
+// Segment types of change messages split by a segmentation enabled
+// subscription, Stream delivers the joined message
+const (
+	SegmentTypeStart = "SEG_START"
+	SegmentType      = "SEG"
+	SegmentTypeEnd   = "SEG_END"
+)
+
+// Markets of a market subscription, see MarketFilter for the betting API
+type StreamMarketFilter struct {
+	MarketIds         []string `json:"marketIds,omitempty"`
+	BspMarket         *bool    `json:"bspMarket,omitempty"`
+	BettingTypes      []string `json:"bettingTypes,omitempty"`
+	EventTypeIds      []string `json:"eventTypeIds,omitempty"`
+	EventIds          []string `json:"eventIds,omitempty"`
+	TurnInPlayEnabled *bool    `json:"turnInPlayEnabled,omitempty"`
+	MarketTypes       []string `json:"marketTypes,omitempty"`
+	Venues            []string `json:"venues,omitempty"`
+	CountryCodes      []string `json:"countryCodes,omitempty"`
+	RaceTypes         []string `json:"raceTypes,omitempty"`
+}
+
+// Data sent for the subscribed markets, LadderLevels limits the depth of
+// EX_BEST_OFFERS and EX_BEST_OFFERS_DISP ladders (1 to 10)
+type MarketDataFilter struct {
+	LadderLevels int      `json:"ladderLevels,omitempty"`
+	Fields       []string `json:"fields,omitempty"`
+}
+
+// Parameters of a market subscription
+type MarketSubscription struct {
+	MarketFilter        *StreamMarketFilter `json:"marketFilter,omitempty"`
+	MarketDataFilter    *MarketDataFilter   `json:"marketDataFilter,omitempty"`
+	ConflateMs          int64               `json:"conflateMs,omitempty"`
+	HeartbeatMs         int64               `json:"heartbeatMs,omitempty"`
+	SegmentationEnabled bool                `json:"segmentationEnabled,omitempty"`
+	InitialClk          string              `json:"initialClk,omitempty"`
+	Clk                 string              `json:"clk,omitempty"`
+}
+
+// Orders of an order subscription, every order of the account by default
+type OrderFilter struct {
+	IncludeOverallPosition        *bool    `json:"includeOverallPosition,omitempty"`
+	AccountIds                    []int64  `json:"accountIds,omitempty"`
+	CustomerStrategyRefs          []string `json:"customerStrategyRefs,omitempty"`
+	PartitionMatchedByStrategyRef bool     `json:"partitionMatchedByStrategyRef,omitempty"`
+}
+
+// Parameters of an order subscription
+type OrderSubscription struct {
+	OrderFilter         *OrderFilter `json:"orderFilter,omitempty"`
+	ConflateMs          int64        `json:"conflateMs,omitempty"`
+	HeartbeatMs         int64        `json:"heartbeatMs,omitempty"`
+	SegmentationEnabled bool         `json:"segmentationEnabled,omitempty"`
+	InitialClk          string       `json:"initialClk,omitempty"`
+	Clk                 string       `json:"clk,omitempty"`
+}
+
+// Price and size of a full ladder, [price, size]
+type PriceVol [2]float64
+
+// Level, price and size of a best offers ladder, [level, price, size]
+type LevelPriceVol [3]float64
+
+// Runner Definition, runner state of a market definition
+type RunnerDefinition struct {
+	Id               uint32
+	Hc               float64
+	Status           string
+	SortPriority     int
+	AdjustmentFactor float64
+	Bsp              float64
+	RemovalDate      string
+}
+
+// Market Definition, sent on subscription and whenever market state changes
+type MarketDefinition struct {
+	Venue                 string
+	RaceType              string
+	SettledTime           string
+	Timezone              string
+	EachWayDivisor        float64
+	BspMarket             bool
+	TurnInPlayEnabled     bool
+	PersistenceEnabled    bool
+	MarketBaseRate        float64
+	EventId               string
+	EventTypeId           string
+	NumberOfWinners       int
+	BettingType           string
+	MarketType            string
+	MarketTime            string
+	SuspendTime           string
+	BspReconciled         bool
+	Complete              bool
+	InPlay                bool
+	CrossMatching         bool
+	RunnersVoidable       bool
+	NumberOfActiveRunners int
+	BetDelay              int
+	Status                string
+	Regulators            []string
+	CountryCode           string
+	DiscountAllowed       bool
+	OpenDate              string
+	Version               int64
+	Runners               []RunnerDefinition
+}
+
+// Runner Change, ladders replace the given price points (levels for best
+// offers), a zero size removes one
+type RunnerChange struct {
+	Id    uint32
+	Hc    float64
+	Con   bool
+	Tv    float64
+	Ltp   float64
+	Spn   float64
+	Spf   float64
+	Atb   []PriceVol
+	Atl   []PriceVol
+	Spb   []PriceVol
+	Spl   []PriceVol
+	Trd   []PriceVol
+	Batb  []LevelPriceVol
+	Batl  []LevelPriceVol
+	Bdatb []LevelPriceVol
+	Bdatl []LevelPriceVol
+}
+
+// Market Change, Img is set when the change replaces the cached market
+type MarketChange struct {
+	Id               string
+	Img              bool
+	Con              bool
+	Tv               float64
+	MarketDefinition *MarketDefinition
+	Rc               []RunnerChange
+}
+
+// Market Change Message (mcm), Pt is the publish time in epoch millis
+type MarketChangeMessage struct {
+	Op          string
+	Id          int
+	Ct          string
+	Clk         string
+	InitialClk  string
+	Pt          int64
+	ConflateMs  int64
+	HeartbeatMs int64
+	SegmentType string
+	Status      int
+	Mc          []MarketChange
+}
+
+// Sides and statuses of stream orders
+const (
+	StreamSideBack                     = "B"
+	StreamSideLay                      = "L"
+	StreamOrderStatusExecutable        = "E"
+	StreamOrderStatusExecutionComplete = "EC"
+)
+
+// Unmatched Order, the full state of an order which was unmatched at some
+// point. Dates are epoch millis.
+type UnmatchedOrder struct {
+	BetId                 string  `json:"id"`
+	Price                 float64 `json:"p"`
+	Size                  float64 `json:"s"`
+	BspLiability          float64 `json:"bsp"`
+	Side                  string  `json:"side"`
+	Status                string  `json:"status"`
+	PersistenceType       string  `json:"pt"`
+	OrderType             string  `json:"ot"`
+	PlacedDate            int64   `json:"pd"`
+	MatchedDate           int64   `json:"md"`
+	CancelledDate         int64   `json:"cd"`
+	LapsedDate            int64   `json:"ld"`
+	LapseStatusReasonCode string  `json:"lsrc"`
+	AveragePriceMatched   float64 `json:"avp"`
+	SizeMatched           float64 `json:"sm"`
+	SizeRemaining         float64 `json:"sr"`
+	SizeLapsed            float64 `json:"sl"`
+	SizeCancelled         float64 `json:"sc"`
+	SizeVoided            float64 `json:"sv"`
+	RegulatorAuthCode     string  `json:"rac"`
+	RegulatorCode         string  `json:"rc"`
+	ReferenceOrder        string  `json:"rfo"`
+	ReferenceStrategy     string  `json:"rfs"`
+}
+
+// Strategy Match Change, matched ladders of a customer strategy ref
+type StrategyMatchChange struct {
+	Mb []PriceVol
+	Ml []PriceVol
+}
+
+// Order Runner Change, Uo orders replace cached orders with the same bet id
+// and Mb/Ml matched backs and lays replace the given price points
+type OrderRunnerChange struct {
+	Id        uint32
+	Hc        float64
+	FullImage bool
+	Uo        []UnmatchedOrder
+	Mb        []PriceVol
+	Ml        []PriceVol
+	Smc       map[string]StrategyMatchChange
+}
+
+// Order Market Change, FullImage is set when the change replaces the cached
+// orders of the market
+type OrderMarketChange struct {
+	Id        string
+	AccountId int64
+	Closed    bool
+	FullImage bool
+	Orc       []OrderRunnerChange
+}
+
+// Order Change Message (ocm), Pt is the publish time in epoch millis
+type OrderChangeMessage struct {
+	Op          string
+	Id          int
+	Ct          string
+	Clk         string
+	InitialClk  string
+	Pt          int64
+	ConflateMs  int64
+	HeartbeatMs int64
+	SegmentType string
+	Status      int
+	Oc          []OrderMarketChange
+}
+
+// Status Message, the answer to authentication and subscription requests,
+// also sent unsolicited before the server closes the connection
+type StatusMessage struct {
+	Op                   string
+	Id                   int
+	StatusCode           string
+	ErrorCode            ErrorCode
+	ErrorMessage         string
+	ConnectionClosed     bool
+	ConnectionId         string
+	ConnectionsAvailable int
+}
+
+// StreamError is a FAILURE status of the stream
+type StreamError struct {
+	Code             ErrorCode
+	Message          string
+	ConnectionClosed bool
+	ConnectionId     string
+}
+
+func (e *StreamError) Error() string {
+	msg := "stream: " + string(e.Code)
+	if e.Code == "" {
+		msg = "stream: connection closed"
+	}
+	if e.Message != "" {
+		msg += " (" + e.Message + ")"
+	}
+	return msg
+}
+
+// matches an ErrorCode target against the status error code
+func (e *StreamError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && e.Code != "" && e.Code == code
+}
+
+// returns the error of a status message, nil if it succeeded
+func (m *StatusMessage) err() error {
+	if m.StatusCode == "SUCCESS" {
+		return nil
+	}
+	return &StreamError{
+		Code:             m.ErrorCode,
+		Message:          m.ErrorMessage,
+		ConnectionClosed: m.ConnectionClosed,
+		ConnectionId:     m.ConnectionId,
+	}
+}
+
+// Events reported by Stream
+type StreamEventType int
+
+const (
+	// the connection was lost, Err is the cause
+	StreamDisconnected StreamEventType = iota + 1
+	// a reconnect attempt failed, Err is the cause
+	StreamReconnectFailed
+	// connected again and subscriptions resumed
+	StreamReconnected
+	// the server sent a status not answering a request
+	StreamStatus
+)
+
+func (t StreamEventType) String() string {
+	switch t {
+	case StreamDisconnected:
+		return "disconnected"
+	case StreamReconnectFailed:
+		return "reconnect failed"
+	case StreamReconnected:
+		return "reconnected"
+	case StreamStatus:
+		return "status"
+	}
+	return fmt.Sprintf("StreamEventType(%d)", int(t))
+}
+
+// StreamEvent reports a connection state change or a status of the server
+type StreamEvent struct {
+	Type    StreamEventType
+	Err     error
+	Status  *StatusMessage
+	Attempt int // reconnect attempt, starting from 1
+}
+
+// Backoff between reconnect attempts unless WithStreamBackoff is given
+const (
+	DefaultStreamMinBackoff = 500 * time.Millisecond
+	DefaultStreamMaxBackoff = 30 * time.Second
+)
+
+// heartbeat interval of the server unless the subscription sets one, the
+// connection is considered dead after missing streamMissedHeartbeats
+const (
+	defaultStreamHeartbeat = 5 * time.Second
+	streamMissedHeartbeats = 3
+)
+
+// settings collected from stream options
+type streamOptions struct {
+	reconnect  bool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	recorder   *Recorder
+}
+
+// StreamOption configures a Stream before it connects
+type StreamOption func(o *streamOptions) error
+
+// Waits min before the first reconnect attempt, doubling the wait after
+// every failed attempt up to max
+func WithStreamBackoff(min, max time.Duration) StreamOption {
+	return func(o *streamOptions) error {
+		if min <= 0 || max < min {
+			return errors.New("stream backoff must be positive and min " +
+				"not above max")
+		}
+		o.minBackoff, o.maxBackoff = min, max
+		return nil
+	}
+}
+
+// Records every line received with rec, see Replayer
+func WithStreamRecorder(rec *Recorder) StreamOption {
+	return func(o *streamOptions) error {
+		if rec == nil {
+			return errors.New("recorder can not be nil")
+		}
+		o.recorder = rec
+		return nil
+	}
+}
+
+// Ends the stream when the connection is lost instead of reconnecting
+func WithoutReconnect() StreamOption {
+	return func(o *streamOptions) error {
+		o.reconnect = false
+		return nil
+	}
+}
+
+// clocks of a subscription, sent again on resubscription so only missed
+// changes are sent
+type streamClocks struct {
+	initialClk string
+	clk        string
+}
+
+func (c *streamClocks) update(initialClk, clk string) {
+	if initialClk != "" {
+		c.initialClk = initialClk
+	}
+	if clk != "" {
+		c.clk = clk
+	}
+}
+
+// a single connection of a Stream
+type streamConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	id   string // connection id assigned by the server
+
+	segments segmenter
+	record   func(line []byte) // set when recording
+
+	// guarded by Stream.mu
+	pending map[int]chan *StatusMessage // requests waiting for a status
+	lost    bool                        // set once reading stopped
+}
+
+// drops market heartbeats unless they change the stale status, see
+// StreamStatusStale
+type heartbeatFilter struct {
+	status int // Status of the latest market change
+}
+
+// reports whether msg is delivered and records its status
+func (f *heartbeatFilter) deliver(msg *MarketChangeMessage) bool {
+	changed := msg.Status != f.status
+	f.status = msg.Status
+	return msg.Ct != ChangeTypeHeartbeat || changed
+}
+
+// partial messages of segmented changes
+type segmenter struct {
+	market *MarketChangeMessage
+	order  *OrderChangeMessage
+}
+
+// returns msg joined with its preceding segments, nil until the last
+// segment arrived
+func (g *segmenter) joinMarket(
+	msg *MarketChangeMessage) *MarketChangeMessage {
+	switch msg.SegmentType {
+	case SegmentTypeStart:
+		g.market = msg
+		return nil
+	case SegmentType, SegmentTypeEnd:
+		if g.market == nil {
+			break
+		}
+		g.market.Mc = append(g.market.Mc, msg.Mc...)
+		if msg.SegmentType == SegmentType {
+			return nil
+		}
+		joined := g.market
+		g.market = nil
+		msg.Mc = joined.Mc
+		if msg.Ct == "" {
+			msg.Ct = joined.Ct
+		}
+		if msg.InitialClk == "" {
+			msg.InitialClk = joined.InitialClk
+		}
+	}
+	msg.SegmentType = ""
+	return msg
+}
+
+// returns msg joined with its preceding segments, nil until the last
+// segment arrived
+func (g *segmenter) joinOrder(msg *OrderChangeMessage) *OrderChangeMessage {
+	switch msg.SegmentType {
+	case SegmentTypeStart:
+		g.order = msg
+		return nil
+	case SegmentType, SegmentTypeEnd:
+		if g.order == nil {
+			break
+		}
+		g.order.Oc = append(g.order.Oc, msg.Oc...)
+		if msg.SegmentType == SegmentType {
+			return nil
+		}
+		joined := g.order
+		g.order = nil
+		msg.Oc = joined.Oc
+		if msg.Ct == "" {
+			msg.Ct = joined.Ct
+		}
+		if msg.InitialClk == "" {
+			msg.InitialClk = joined.InitialClk
+		}
+	}
+	msg.SegmentType = ""
+	return msg
+}
+
+// writes msg as a CRLF terminated json line
+func (c *streamConn) write(msg []byte) error {
+	if _, err := c.conn.Write(append(msg, '\r', '\n')); err != nil {
+		return fmt.Errorf("stream: %w", err)
+	}
+	return nil
+}
+
+// returns the next line without its CRLF
+func (c *streamConn) readLine() ([]byte, error) {
+	line, err := c.r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = bytes.TrimRight(line, "\r\n")
+	if c.record != nil {
+		c.record(line)
+	}
+	return line, nil
+}
+
+// Stream is a connection to the Exchange Stream API. Change messages of
+// subscriptions are delivered on channels which are closed when the stream
+// ends, Err reports why. Channels of every subscription must be drained or
+// reading from the connection stops.
+//
+// A lost connection is reconnected with exponential backoff, the
+// subscriptions are sent again with their last clocks so the server only
+// sends the changes missed meanwhile. Segmented changes are joined before
+// they are delivered.
+type Stream struct {
+	s    *Session
+	opts streamOptions
+
+	wmu          sync.Mutex // serializes writes
+	mu           sync.Mutex // guards fields below
+	c            *streamConn
+	id           int
+	connected    chan struct{} // closed and replaced when c changes
+	err          error
+	marketSub    *MarketSubscription
+	orderSub     *OrderSubscription
+	marketClocks streamClocks
+	orderClocks  streamClocks
+	heartbeats   heartbeatFilter // of market changes
+
+	// time without messages before the connection is considered dead, per
+	// subscription and zero until subscribed
+	marketTimeout time.Duration
+	orderTimeout  time.Duration
+
+	// held while subscriptions are sent, see lockSubscriptions
+	subscribing chan struct{}
+
+	markets chan *MarketChangeMessage
+	orders  chan *OrderChangeMessage
+	events  chan StreamEvent
+	connErr chan error // reading of the current connection stopped
+
+	ctx     context.Context // canceled by Close
+	cancel  context.CancelFunc
+	stopped chan struct{} // closed once the stream ended
+}
+
+// Connects to the stream endpoint and authenticates with the session token
+// and application key, an expired session is logged in again
+func (s *Session) ConnectStream(ctx context.Context, opts ...StreamOption) (
+	*Stream, error) {
+	o := streamOptions{
+		reconnect:  true,
+		minBackoff: DefaultStreamMinBackoff,
+		maxBackoff: DefaultStreamMaxBackoff,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	st := &Stream{
+		s:           s,
+		opts:        o,
+		markets:     make(chan *MarketChangeMessage, 64),
+		orders:      make(chan *OrderChangeMessage, 64),
+		events:      make(chan StreamEvent, 16),
+		connErr:     make(chan error, 1),
+		stopped:     make(chan struct{}),
+		connected:   make(chan struct{}),
+		subscribing: make(chan struct{}, 1),
+	}
+	st.ctx, st.cancel = context.WithCancel(context.Background())
+
+	c, err := st.connect(ctx)
+	if err != nil {
+		st.cancel()
+		return nil, err
+	}
+	st.start(c)
+
+	go st.run()
+	return st, nil
+}
+
+// returns TLS settings of the session http client, so WithRootCAs also
+// applies to the stream
+func (s *Session) streamTLSConfig() *tls.Config {
+	if t, ok := s.httpClient.Transport.(*http.Transport); ok &&
+		t.TLSClientConfig != nil {
+		return t.TLSClientConfig.Clone()
+	}
+	return &tls.Config{}
+}
+
+// dials and authenticates, logging in again once if the session expired
+func (st *Stream) connect(ctx context.Context) (*streamConn, error) {
+	s := st.s
+	if s.sessionToken() == "" {
+		if err := s.relogin(ctx, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	token := s.sessionToken()
+	c, err := st.dial(ctx)
+	if err != nil && (errors.Is(err, ErrInvalidSessionInformation) ||
+		errors.Is(err, ErrNoSession)) {
+		s.log().Info("session expired, logging in again")
+		if lerr := s.relogin(ctx, token); lerr != nil {
+			return nil, fmt.Errorf("%w (re-login failed: %v)", err, lerr)
+		}
+		c, err = st.dial(ctx)
+	}
+	return c, err
+}
+
+// dials the stream endpoint, reads the connection message and
+// authenticates, ctx bounds all of them
+func (st *Stream) dial(ctx context.Context) (*streamConn, error) {
+	addr := st.s.endpoints.Stream
+	if addr == "" {
+		return nil, errors.New("stream: no stream endpoint")
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	config := st.s.streamTLSConfig()
+	config.ServerName = host
+
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+
+	c := &streamConn{conn: conn, r: bufio.NewReader(conn),
+		pending: make(map[int]chan *StatusMessage)}
+	if rec := st.opts.recorder; rec != nil {
+		c.record = func(line []byte) {
+			if err := rec.Record(time.Now(), line); err != nil {
+				st.s.log().Warn("stream recording failed", "err", err)
+			}
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	err = st.authenticate(c)
+	if !stop() || ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	st.s.log().Info("stream connected", "addr", addr, "connectionId", c.id)
+	return c, nil
+}
+
+// reads the connection message and sends the authentication request
+func (st *Stream) authenticate(c *streamConn) error {
+	var connection struct {
+		Op           string
+		ConnectionId string
+	}
+	line, err := c.readLine()
+	if err == nil {
+		err = json.Unmarshal(line, &connection)
+	}
+	if err == nil && connection.Op != "connection" {
+		err = fmt.Errorf("unexpected %q message", connection.Op)
+	}
+	if err != nil {
+		return fmt.Errorf("stream: connection: %w", err)
+	}
+	c.id = connection.ConnectionId
+
+	msg, err := json.Marshal(map[string]interface{}{
+		"op":      "authentication",
+		"id":      st.nextId(),
+		"appKey":  st.s.applicationKey(),
+		"session": st.s.sessionToken(),
+	})
+	if err != nil {
+		return fmt.Errorf("stream: encoding message: %w", err)
+	}
+	if err := c.write(msg); err != nil {
+		return err
+	}
+
+	var status StatusMessage
+	line, err = c.readLine()
+	if err == nil {
+		err = json.Unmarshal(line, &status)
+	}
+	if err != nil {
+		return fmt.Errorf("stream: authentication: %w", err)
+	}
+	return status.err()
+}
+
+// makes c the current connection and starts reading it
+func (st *Stream) start(c *streamConn) {
+	st.mu.Lock()
+	st.c = c
+	close(st.connected)
+	st.connected = make(chan struct{})
+	st.mu.Unlock()
+
+	stop := context.AfterFunc(st.ctx, func() { c.conn.Close() })
+	go func() {
+		err := st.readLoop(c)
+		stop()
+		st.connErr <- err
+	}()
+}
+
+// reconnects whenever reading stops until the stream is closed or the
+// error is permanent
+func (st *Stream) run() {
+	for {
+		err := <-st.connErr
+		if st.ctx.Err() != nil {
+			st.stop(nil)
+			return
+		}
+		st.s.log().Warn("stream disconnected", "err", err)
+		if !st.opts.reconnect || !streamRetryable(err) {
+			st.stop(err)
+			return
+		}
+		st.event(StreamEvent{Type: StreamDisconnected, Err: err})
+
+		if err := st.reconnect(); err != nil {
+			if st.ctx.Err() != nil {
+				err = nil
+			}
+			st.stop(err)
+			return
+		}
+	}
+}
+
+// reconnects with backoff until it succeeds, the stream is closed or the
+// error is permanent
+func (st *Stream) reconnect() error {
+	backoff := st.opts.minBackoff
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-st.ctx.Done():
+			timer.Stop()
+			return st.ctx.Err()
+		case <-timer.C:
+		}
+
+		err := st.resume()
+		if err == nil {
+			st.s.log().Info("stream reconnected", "attempt", attempt)
+			st.event(StreamEvent{Type: StreamReconnected, Attempt: attempt})
+			return nil
+		}
+		if st.ctx.Err() != nil {
+			return st.ctx.Err()
+		}
+		st.s.log().Warn("stream reconnect failed", "attempt", attempt,
+			"err", err)
+		if !streamRetryable(err) {
+			return err
+		}
+		st.event(StreamEvent{Type: StreamReconnectFailed, Err: err,
+			Attempt: attempt})
+
+		if backoff *= 2; backoff > st.opts.maxBackoff {
+			backoff = st.opts.maxBackoff
+		}
+	}
+}
+
+// connects again and resends the subscriptions with their last clocks
+func (st *Stream) resume() error {
+	ctx, cancel := context.WithTimeout(st.ctx, st.opts.maxBackoff+
+		10*time.Second)
+	defer cancel()
+
+	c, err := st.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	// new subscriptions wait until the stored ones are sent again
+	select {
+	case st.subscribing <- struct{}{}:
+	case <-ctx.Done():
+		c.conn.Close()
+		return ctx.Err()
+	}
+	defer st.unlockSubscriptions()
+	st.start(c)
+
+	st.mu.Lock()
+	var market *MarketSubscription
+	var order *OrderSubscription
+	if st.marketSub != nil {
+		sub := *st.marketSub
+		sub.InitialClk = st.marketClocks.initialClk
+		sub.Clk = st.marketClocks.clk
+		market = &sub
+	}
+	if st.orderSub != nil {
+		sub := *st.orderSub
+		sub.InitialClk = st.orderClocks.initialClk
+		sub.Clk = st.orderClocks.clk
+		order = &sub
+	}
+	st.mu.Unlock()
+
+	if market != nil {
+		err = st.request(ctx, "marketSubscription", market)
+	}
+	if err == nil && order != nil {
+		err = st.request(ctx, "orderSubscription", order)
+	}
+	if err != nil {
+		// resumed clocks are rejected, subscribe afresh next time
+		if errors.Is(err, ErrInvalidClock) {
+			st.mu.Lock()
+			st.marketClocks = streamClocks{}
+			st.orderClocks = streamClocks{}
+			st.mu.Unlock()
+		}
+		c.conn.Close()
+		<-st.connErr
+		return err
+	}
+	return nil
+}
+
+// reports whether a new connection may succeed after err
+func streamRetryable(err error) bool {
+	for _, code := range []ErrorCode{ErrNoAppKey, ErrInvalidAppKey,
+		ErrNotAuthorized, ErrInvalidInput, ErrInvalidRequest,
+		ErrSubscriptionLimitExceeded} {
+		if errors.Is(err, code) {
+			return false
+		}
+	}
+	return true
+}
+
+// reports e without blocking, events are dropped if nobody reads them
+func (st *Stream) event(e StreamEvent) {
+	select {
+	case st.events <- e:
+	default:
+	}
+}
+
+// Returns the connection id assigned by the server to the current
+// connection, useful when reporting issues to Betfair
+func (st *Stream) ConnectionId() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.c.id
+}
+
+// Returns market change messages of the market subscription. Heartbeats are
+// left out unless they change Status, see StreamStatusStale.
+func (st *Stream) MarketChanges() <-chan *MarketChangeMessage {
+	return st.markets
+}
+
+// Returns order change messages of the order subscription
+func (st *Stream) OrderChanges() <-chan *OrderChangeMessage {
+	return st.orders
+}
+
+// Returns reconnects and unsolicited statuses, events are dropped while
+// the channel is full so it need not be read
+func (st *Stream) Events() <-chan StreamEvent {
+	return st.events
+}
+
+// Returns the error which ended the stream, nil while it is running or if
+// it was closed by Close
+func (st *Stream) Err() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.err
+}
+
+// Subscribes to markets, the returned error is the subscription status.
+// A new market subscription replaces the previous one and is resumed after
+// reconnects, if it fails the previous one is resumed instead. While the
+// stream reconnects it waits for the new connection.
+func (st *Stream) SubscribeMarkets(ctx context.Context,
+	sub *MarketSubscription) error {
+	if sub == nil {
+		return errNilRequest("marketSubscription")
+	}
+
+	return st.subscribe(ctx, "marketSubscription", sub, func() func() {
+		stored := *sub
+		prev, prevClocks, prevTimeout := st.marketSub, st.marketClocks,
+			st.marketTimeout
+		st.marketSub = &stored
+		st.marketClocks = streamClocks{sub.InitialClk, sub.Clk}
+		st.marketTimeout = heartbeatTimeout(sub.HeartbeatMs, sub.ConflateMs)
+		return func() {
+			st.marketSub, st.marketClocks = prev, prevClocks
+			st.marketTimeout = prevTimeout
+		}
+	})
+}
+
+// Subscribes to orders of the account, the returned error is the
+// subscription status. A new order subscription replaces the previous one
+// and is resumed after reconnects, if it fails the previous one is resumed
+// instead. While the stream reconnects it waits for the new connection.
+func (st *Stream) SubscribeOrders(ctx context.Context,
+	sub *OrderSubscription) error {
+	if sub == nil {
+		return errNilRequest("orderSubscription")
+	}
+
+	return st.subscribe(ctx, "orderSubscription", sub, func() func() {
+		stored := *sub
+		prev, prevClocks, prevTimeout := st.orderSub, st.orderClocks,
+			st.orderTimeout
+		st.orderSub = &stored
+		st.orderClocks = streamClocks{sub.InitialClk, sub.Clk}
+		st.orderTimeout = heartbeatTimeout(sub.HeartbeatMs, sub.ConflateMs)
+		return func() {
+			st.orderSub, st.orderClocks = prev, prevClocks
+			st.orderTimeout = prevTimeout
+		}
+	})
+}
+
+// stores a subscription with store and sends it on a live connection. store
+// runs with st.mu held and returns a func restoring the previous
+// subscription, which is called if sending it fails.
+func (st *Stream) subscribe(ctx context.Context, op string,
+	params interface{}, store func() (restore func())) error {
+	if err := st.lockSubscriptions(ctx); err != nil {
+		return err
+	}
+	defer st.unlockSubscriptions()
+
+	st.mu.Lock()
+	restore := store()
+	st.mu.Unlock()
+
+	err := st.request(ctx, op, params)
+	if err != nil {
+		st.mu.Lock()
+		restore()
+		st.mu.Unlock()
+	}
+	return err
+}
+
+// takes the subscription lock once the current connection is live. Sending
+// subscriptions and resuming them after a reconnect are serialized, so the
+// stored subscriptions are the ones the server has.
+func (st *Stream) lockSubscriptions(ctx context.Context) error {
+	for {
+		select {
+		case st.subscribing <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-st.stopped:
+			return st.stoppedErr()
+		}
+
+		st.mu.Lock()
+		lost, connected := st.c.lost, st.connected
+		st.mu.Unlock()
+		if !lost {
+			return nil
+		}
+
+		// wait for the reconnect, it resumes the stored subscriptions first
+		st.unlockSubscriptions()
+		select {
+		case <-connected:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-st.stopped:
+			return st.stoppedErr()
+		}
+	}
+}
+
+func (st *Stream) unlockSubscriptions() {
+	<-st.subscribing
+}
+
+// Sends a heartbeat, the returned error is its status
+func (st *Stream) Heartbeat(ctx context.Context) error {
+	return st.request(ctx, "heartbeat", struct{}{})
+}
+
+// Closes the connection and stops reconnecting, change channels are closed
+// once reading stops
+func (st *Stream) Close() error {
+	st.cancel()
+	<-st.stopped
+	return nil
+}
+
+// returns the time without messages before a subscription with the given
+// heartbeat and conflation is considered dead
+func heartbeatTimeout(heartbeatMs, conflateMs int64) time.Duration {
+	heartbeat := defaultStreamHeartbeat
+	if heartbeatMs > 0 {
+		heartbeat = time.Duration(heartbeatMs) * time.Millisecond
+	}
+	return streamMissedHeartbeats*heartbeat +
+		time.Duration(conflateMs)*time.Millisecond
+}
+
+// returns the longest timeout of the current subscriptions, zero until
+// subscribed, st.mu must be held
+func (st *Stream) readTimeout() time.Duration {
+	if st.marketTimeout > st.orderTimeout {
+		return st.marketTimeout
+	}
+	return st.orderTimeout
+}
+
+// sends the op with params on the current connection and waits for its
+// status, the request fails if that connection is lost first
+func (st *Stream) request(ctx context.Context, op string,
+	params interface{}) error {
+	select {
+	case <-st.stopped:
+		return st.stoppedErr()
+	default:
+	}
+
+	id := st.nextId()
+	wait := make(chan *StatusMessage, 1)
+	st.mu.Lock()
+	c := st.c
+	if c.lost {
+		st.mu.Unlock()
+		return errConnectionLost(op)
+	}
+	c.pending[id] = wait
+	st.mu.Unlock()
+	defer func() {
+		st.mu.Lock()
+		delete(c.pending, id)
+		st.mu.Unlock()
+	}()
+
+	msg, err := withOp(op, id, params)
+	if err != nil {
+		return err
+	}
+	if err := st.send(c, msg); err != nil {
+		return err
+	}
+
+	select {
+	case status, ok := <-wait:
+		if !ok {
+			return errConnectionLost(op)
+		}
+		return status.err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func errConnectionLost(op string) error {
+	return fmt.Errorf("%s: stream connection lost", op)
+}
+
+// returns params encoded as an object with op and id set
+func withOp(op string, id int, params interface{}) (map[string]interface{},
+	error) {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: encoding request: %w", op, err)
+	}
+
+	msg := map[string]interface{}{}
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return nil, fmt.Errorf("%s: encoding request: %w", op, err)
+	}
+	msg["op"] = op
+	msg["id"] = id
+	return msg, nil
+}
+
+func (st *Stream) nextId() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.id++
+	return st.id
+}
+
+// writes msg to c
+func (st *Stream) send(c *streamConn, msg interface{}) error {
+	p, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("stream: encoding message: %w", err)
+	}
+	st.s.log().Debug("stream send", "message", string(p))
+
+	st.wmu.Lock()
+	defer st.wmu.Unlock()
+	return c.write(p)
+}
+
+// reads and dispatches messages of c until it fails or is closed, pending
+// requests of c fail once it returns
+func (st *Stream) readLoop(c *streamConn) error {
+	defer st.failPending(c)
+
+	for {
+		st.mu.Lock()
+		timeout := st.readTimeout()
+		st.mu.Unlock()
+		if timeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if err := st.handleLine(c, line); err != nil {
+			c.conn.Close()
+			return err
+		}
+	}
+}
+
+// decodes a message line and dispatches it by op
+func (st *Stream) handleLine(c *streamConn, line []byte) error {
+	st.s.log().Debug("stream receive", "message", string(line))
+
+	var head struct {
+		Op string
+		Id int
+	}
+	if err := json.Unmarshal(line, &head); err != nil {
+		return fmt.Errorf("stream: decoding message: %w", err)
+	}
+
+	switch head.Op {
+	case "status":
+		var status StatusMessage
+		if err := json.Unmarshal(line, &status); err != nil {
+			return fmt.Errorf("stream: decoding status: %w", err)
+		}
+		return st.handleStatus(c, &status)
+	case "mcm":
+		var msg MarketChangeMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return fmt.Errorf("stream: decoding mcm: %w", err)
+		}
+		st.handleMarketChange(c, &msg)
+	case "ocm":
+		var msg OrderChangeMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return fmt.Errorf("stream: decoding ocm: %w", err)
+		}
+		st.handleOrderChange(c, &msg)
+	default:
+		st.s.log().Debug("stream message ignored", "op", head.Op)
+	}
+	return nil
+}
+
+// joins segments, records clocks and delivers the change
+func (st *Stream) handleMarketChange(c *streamConn, msg *MarketChangeMessage) {
+	if msg = c.segments.joinMarket(msg); msg == nil {
+		return
+	}
+
+	st.mu.Lock()
+	st.marketClocks.update(msg.InitialClk, msg.Clk)
+	if msg.HeartbeatMs > 0 {
+		st.marketTimeout = heartbeatTimeout(msg.HeartbeatMs, msg.ConflateMs)
+	}
+	deliver := st.heartbeats.deliver(msg)
+	st.mu.Unlock()
+
+	if !deliver {
+		return
+	}
+	select {
+	case st.markets <- msg:
+	case <-st.ctx.Done():
+	}
+}
+
+// joins segments, records clocks and delivers the change
+func (st *Stream) handleOrderChange(c *streamConn, msg *OrderChangeMessage) {
+	if msg = c.segments.joinOrder(msg); msg == nil {
+		return
+	}
+
+	st.mu.Lock()
+	st.orderClocks.update(msg.InitialClk, msg.Clk)
+	if msg.HeartbeatMs > 0 {
+		st.orderTimeout = heartbeatTimeout(msg.HeartbeatMs, msg.ConflateMs)
+	}
+	st.mu.Unlock()
+
+	if msg.Ct == ChangeTypeHeartbeat {
+		return
+	}
+	select {
+	case st.orders <- msg:
+	case <-st.ctx.Done():
+	}
+}
+
+// delivers status to its request sent on c, a status closing the
+// connection ends reading it
+func (st *Stream) handleStatus(c *streamConn, status *StatusMessage) error {
+	st.mu.Lock()
+	wait := c.pending[status.Id]
+	st.mu.Unlock()
+	if wait != nil {
+		// buffered for one status, a duplicate must not block reading
+		select {
+		case wait <- status:
+		default:
+		}
+	} else {
+		st.event(StreamEvent{Type: StreamStatus, Err: status.err(),
+			Status: status})
+	}
+
+	if status.ConnectionClosed {
+		if err := status.err(); err != nil {
+			return err
+		}
+		return &StreamError{ConnectionClosed: true,
+			ConnectionId: status.ConnectionId}
+	}
+	return nil
+}
+
+// marks c lost and fails its requests waiting for a status
+func (st *Stream) failPending(c *streamConn) {
+	st.mu.Lock()
+	c.lost = true
+	for id, wait := range c.pending {
+		close(wait)
+		delete(c.pending, id)
+	}
+	st.mu.Unlock()
+}
+
+// records err and closes change channels
+func (st *Stream) stop(err error) {
+	st.mu.Lock()
+	st.err = err
+	st.mu.Unlock()
+
+	close(st.stopped)
+	close(st.markets)
+	close(st.orders)
+	close(st.events)
+	st.cancel()
+}
+
+// returns the error of requests sent after the stream ended
+func (st *Stream) stoppedErr() error {
+	if err := st.Err(); err != nil {
+		return err
+	}
+	return net.ErrClosed
+}
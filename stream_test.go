@@ -0,0 +1,534 @@
+package betfair
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// server side of a stand-in stream connection
+type streamTestConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// writes a CRLF terminated message line
+func (c *streamTestConn) send(format string, args ...interface{}) {
+	fmt.Fprintf(c.conn, format+"\r\n", args...)
+}
+
+// reads a client message, nil once the client disconnected
+func (c *streamTestConn) read() map[string]interface{} {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		c.t.Errorf("message not CRLF terminated: %q", line)
+	}
+	msg := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		c.t.Errorf("invalid message %q", line)
+	}
+	return msg
+}
+
+// sends the connection message and accepts authentication
+func (c *streamTestConn) accept() {
+	c.send(`{"op":"connection","connectionId":"conn-1"}`)
+	auth := c.read()
+	if auth["op"] != "authentication" || auth["session"] != "token" ||
+		auth["appKey"] != "appKey" {
+		c.t.Error("unexpected authentication", auth)
+	}
+	c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS",`+
+		`"connectionClosed":false}`, auth["id"])
+}
+
+// serves every stream connection with serve on a local TLS listener,
+// returns a logged in session trusting it
+func newStreamTestSession(t *testing.T,
+	serve func(n int, c *streamTestConn)) *Session {
+	// borrow the certificate of httptest, valid for 127.0.0.1
+	srv := httptest.NewTLSServer(nil)
+	certs := srv.TLS.Certificates
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	srv.Close()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: certs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for n := 0; ; n++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(n int) {
+				defer conn.Close()
+				serve(n, &streamTestConn{t: t, conn: conn,
+					r: bufio.NewReader(conn)})
+			}(n)
+		}
+	}()
+
+	c, _ := NewInteractiveCredentials("userName", "passWord", "UK", "appKey")
+	s, err := New(context.Background(), c, WithLogger(nil), WithoutLogin(),
+		WithRootCAs(pool), WithEndpoints(Endpoints{
+			Identity: "http://127.0.0.1:1/api/",
+			Betting:  "http://127.0.0.1:1/betting/",
+			Account:  "http://127.0.0.1:1/account/",
+			Stream:   l.Addr().String(),
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.setToken("token")
+	return s
+}
+
+func Test_StreamMarketSubscription(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+
+		sub := c.read()
+		filter, _ := sub["marketDataFilter"].(map[string]interface{})
+		if sub["op"] != "marketSubscription" || filter["ladderLevels"] != 3.0 {
+			t.Error("unexpected subscription", sub)
+		}
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"initialClk":"ic","clk":"c1",`+
+			`"pt":1500000000000,"ct":"SUB_IMAGE","mc":[{"id":"1.2",`+
+			`"img":true,"rc":[{"id":10,"atb":[[2.5,10]],"ltp":2.52}]}]}`,
+			sub["id"])
+		c.read()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if st.ConnectionId() != "conn-1" {
+		t.Error("connection id not set")
+	}
+
+	err = st.SubscribeMarkets(ctx, &MarketSubscription{
+		MarketFilter: &StreamMarketFilter{MarketIds: []string{"1.2"}},
+		MarketDataFilter: &MarketDataFilter{
+			LadderLevels: 3,
+			Fields:       []string{FieldExBestOffers, FieldExLTP},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-st.MarketChanges():
+		if msg.Ct != ChangeTypeSubImage || msg.Clk != "c1" ||
+			len(msg.Mc) != 1 || msg.Mc[0].Rc[0].Atb[0] != (PriceVol{2.5, 10}) {
+			t.Error("unexpected market change", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("no market change received")
+	}
+
+	st.Close()
+	for range st.MarketChanges() {
+	}
+	if st.Err() != nil {
+		t.Error("closed stream reported error", st.Err())
+	}
+}
+
+func Test_StreamStaleHeartbeats(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		sub := c.read()
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"ct":"HEARTBEAT","clk":"c1"}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"ct":"HEARTBEAT","clk":"c2",`+
+			`"status":503}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"ct":"HEARTBEAT","clk":"c3",`+
+			`"status":503}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"ct":"HEARTBEAT","clk":"c4"}`, sub["id"])
+		c.send(`{"op":"mcm","id":%v,"clk":"c5","mc":[{"id":"1.2"}]}`,
+			sub["id"])
+		c.read()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// only heartbeats changing the stale status are delivered
+	var clks []string
+	for len(clks) < 3 {
+		select {
+		case msg := <-st.MarketChanges():
+			clks = append(clks, fmt.Sprintf("%s/%d", msg.Clk, msg.Status))
+		case <-ctx.Done():
+			t.Fatal("market changes missing", clks)
+		}
+	}
+	if strings.Join(clks, " ") != "c2/503 c4/0 c5/0" {
+		t.Error("unexpected market changes", clks)
+	}
+}
+
+func Test_StreamAuthenticationFailure(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.send(`{"op":"connection","connectionId":"conn-1"}`)
+		auth := c.read()
+		c.send(`{"op":"status","id":%v,"statusCode":"FAILURE",`+
+			`"errorCode":"NO_APP_KEY","errorMessage":"AppKey is not valid",`+
+			`"connectionClosed":true}`, auth["id"])
+	})
+
+	_, err := s.ConnectStream(context.Background())
+	var e *StreamError
+	if !errors.Is(err, ErrNoAppKey) || !errors.As(err, &e) ||
+		!e.ConnectionClosed {
+		t.Error("authentication failure not reported", err)
+	}
+}
+
+func Test_StreamOrderSubscription(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+
+		sub := c.read()
+		if sub["op"] != "orderSubscription" {
+			t.Error("unexpected subscription", sub)
+		}
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+		c.send(`{"op":"ocm","id":%v,"clk":"c1","pt":1500000000000,`+
+			`"oc":[{"id":"1.2","orc":[{"id":10,"uo":[{"id":"b1","p":2.5,`+
+			`"s":10,"side":"B","status":"E","sr":10}]}]}]}`, sub["id"])
+		c.read()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	if err := st.SubscribeOrders(ctx, &OrderSubscription{}); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewOrderCache()
+	select {
+	case msg := <-st.OrderChanges():
+		cache.Apply(msg)
+	case <-ctx.Done():
+		t.Fatal("no order change received")
+	}
+	if o, ok := cache.Order("1.2", 10, "b1"); !ok || o.SizeRemaining != 10 {
+		t.Error("order not received", o)
+	}
+}
+
+// waits for the next event of type typ
+func waitStreamEvent(t *testing.T, st *Stream,
+	typ StreamEventType) StreamEvent {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-st.Events():
+			if !ok {
+				t.Fatal("stream ended", st.Err())
+			}
+			if e.Type == typ {
+				return e
+			}
+		case <-timeout:
+			t.Fatal("no event", typ)
+		}
+	}
+}
+
+func Test_StreamReconnect(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		sub := c.read()
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+
+		switch n {
+		case 0:
+			if sub["clk"] != nil {
+				t.Error("first subscription resumed", sub)
+			}
+			// a segmented image
+			c.send(`{"op":"mcm","id":%v,"ct":"SUB_IMAGE","segmentType":`+
+				`"SEG_START","initialClk":"ic1","mc":[{"id":"1.2","img":true}]}`,
+				sub["id"])
+			c.send(`{"op":"mcm","id":%v,"segmentType":"SEG_END","clk":"c1",`+
+				`"mc":[{"id":"1.3","img":true}]}`, sub["id"])
+			c.send(`{"op":"mcm","id":%v,"clk":"c2","mc":[{"id":"1.2",`+
+				`"tv":5}]}`, sub["id"])
+			c.send(`{"op":"status","statusCode":"FAILURE",` +
+				`"errorCode":"TIMEOUT","connectionClosed":true}`)
+		case 1:
+			if sub["initialClk"] != "ic1" || sub["clk"] != "c2" ||
+				sub["heartbeatMs"] != 1000.0 {
+				t.Error("subscription not resumed", sub)
+			}
+			c.send(`{"op":"mcm","id":%v,"ct":"RESUB_DELTA","clk":"c3",`+
+				`"mc":[{"id":"1.2","tv":6}]}`, sub["id"])
+			c.read()
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx, WithStreamBackoff(10*time.Millisecond,
+		50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{
+		HeartbeatMs:         1000,
+		SegmentationEnabled: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []*MarketChangeMessage
+	for len(msgs) < 3 {
+		select {
+		case msg := <-st.MarketChanges():
+			msgs = append(msgs, msg)
+		case <-ctx.Done():
+			t.Fatal("changes not received", len(msgs))
+		}
+	}
+	if len(msgs[0].Mc) != 2 || msgs[0].Ct != ChangeTypeSubImage ||
+		msgs[0].SegmentType != "" {
+		t.Error("segments not joined", msgs[0])
+	}
+	if msgs[2].Ct != ChangeTypeResubDelta || msgs[2].Clk != "c3" {
+		t.Error("resumed changes not received", msgs[2])
+	}
+
+	e := waitStreamEvent(t, st, StreamStatus)
+	if !errors.Is(e.Err, ErrTimeout) || !e.Status.ConnectionClosed {
+		t.Error("closing status not reported", e)
+	}
+	e = waitStreamEvent(t, st, StreamDisconnected)
+	if !errors.Is(e.Err, ErrTimeout) {
+		t.Error("disconnect cause not reported", e.Err)
+	}
+	waitStreamEvent(t, st, StreamReconnected)
+}
+
+func Test_StreamHeartbeatTimeout(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		// no heartbeats are sent
+		for sub := c.read(); sub != nil; sub = c.read() {
+			c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`,
+				sub["id"])
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx, WithStreamBackoff(10*time.Millisecond,
+		50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{
+		HeartbeatMs: 60000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// a replacing subscription with a shorter heartbeat shortens the timeout
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{
+		HeartbeatMs: 20,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := waitStreamEvent(t, st, StreamDisconnected)
+	var ne net.Error
+	if !errors.As(e.Err, &ne) || !ne.Timeout() {
+		t.Error("missed heartbeats not detected", e.Err)
+	}
+	waitStreamEvent(t, st, StreamReconnected)
+}
+
+func Test_StreamWithoutReconnect(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		c.send(`{"op":"status","statusCode":"FAILURE",` +
+			`"errorCode":"CONNECTION_FAILED","connectionClosed":true}`)
+	})
+
+	st, err := s.ConnectStream(context.Background(), WithoutReconnect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range st.MarketChanges() {
+	}
+	if !errors.Is(st.Err(), ErrConnectionFailed) {
+		t.Error("stream error not reported", st.Err())
+	}
+	st.Close()
+}
+
+func Test_StreamRejectedSubscription(t *testing.T) {
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		sub := c.read()
+		filter, _ := sub["marketFilter"].(map[string]interface{})
+		if fmt.Sprint(filter["marketIds"]) != "[1.2]" {
+			t.Error("unexpected subscription", n, sub)
+		}
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+		if n > 0 {
+			c.read()
+			return
+		}
+
+		// a duplicate status must not stop reading
+		c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`, sub["id"])
+
+		sub = c.read()
+		c.send(`{"op":"status","id":%v,"statusCode":"FAILURE",`+
+			`"errorCode":"SUBSCRIPTION_LIMIT_EXCEEDED",`+
+			`"connectionClosed":false}`, sub["id"])
+
+		hb := c.read()
+		c.send(`{"op":"status","id":%v,"statusCode":"FAILURE",`+
+			`"errorCode":"TIMEOUT","connectionClosed":true}`, hb["id"])
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx, WithStreamBackoff(10*time.Millisecond,
+		50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{
+		MarketFilter: &StreamMarketFilter{MarketIds: []string{"1.2"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err = st.SubscribeMarkets(ctx, &MarketSubscription{
+		MarketFilter: &StreamMarketFilter{MarketIds: []string{"1.3"}},
+	})
+	if !errors.Is(err, ErrSubscriptionLimitExceeded) {
+		t.Error("rejected subscription not reported", err)
+	}
+
+	// the accepted subscription is resumed after the connection is closed
+	if err := st.Heartbeat(ctx); !errors.Is(err, ErrTimeout) {
+		t.Error("heartbeat status not returned", err)
+	}
+	waitStreamEvent(t, st, StreamReconnected)
+
+	closed := make(chan struct{})
+	go func() {
+		st.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		t.Fatal("Close blocked")
+	}
+	if st.Err() != nil {
+		t.Error("stream ended", st.Err())
+	}
+}
+
+func Test_StreamSubscribeWhileReconnecting(t *testing.T) {
+	resumed := make(chan map[string]interface{}, 1)
+	s := newStreamTestSession(t, func(n int, c *streamTestConn) {
+		c.accept()
+		switch n {
+		case 0:
+			// drops the connection before anything is subscribed
+			c.send(`{"op":"status","statusCode":"FAILURE",` +
+				`"errorCode":"TIMEOUT","connectionClosed":true}`)
+		case 1:
+			sub := c.read()
+			c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`,
+				sub["id"])
+			c.send(`{"op":"status","statusCode":"FAILURE",` +
+				`"errorCode":"TIMEOUT","connectionClosed":true}`)
+		default:
+			sub := c.read()
+			c.send(`{"op":"status","id":%v,"statusCode":"SUCCESS"}`,
+				sub["id"])
+			resumed <- sub
+			c.read()
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := s.ConnectStream(ctx, WithStreamBackoff(50*time.Millisecond,
+		100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	// sent on the new connection once it is established
+	waitStreamEvent(t, st, StreamDisconnected)
+	if err := st.SubscribeMarkets(ctx, &MarketSubscription{
+		MarketFilter: &StreamMarketFilter{MarketIds: []string{"1.2"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// and kept for later reconnects
+	select {
+	case sub := <-resumed:
+		filter, _ := sub["marketFilter"].(map[string]interface{})
+		if sub["op"] != "marketSubscription" ||
+			fmt.Sprint(filter["marketIds"]) != "[1.2]" {
+			t.Error("subscription not resumed", sub)
+		}
+	case <-ctx.Done():
+		t.Fatal("subscription not resumed")
+	}
+}
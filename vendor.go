@@ -0,0 +1,271 @@
+package betfair
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Statuses of application subscriptions
+const (
+	SubscriptionStatusAll         = "ALL"
+	SubscriptionStatusActivated   = "ACTIVATED"
+	SubscriptionStatusUnactivated = "UNACTIVATED"
+	SubscriptionStatusCancelled   = "CANCELLED"
+	SubscriptionStatusExpired     = "EXPIRED"
+)
+
+// Grant types of the OAuth token exchange
+const (
+	GrantTypeAuthorizationCode = "AUTHORIZATION_CODE"
+	GrantTypeRefreshToken      = "REFRESH_TOKEN"
+)
+
+// Application Subscription, a subscription token of a vendor application
+type ApplicationSubscription struct {
+	SubscriptionToken    string
+	ExpiryDateTime       time.Time
+	ExpiredDateTime      time.Time
+	CreatedDateTime      time.Time
+	ActivationDateTime   time.Time
+	CancellationDateTime time.Time
+	SubscriptionStatus   string
+	ClientReference      string
+	VendorClientId       string
+}
+
+// Subscription Token Info, a subscription token held by the account
+type SubscriptionTokenInfo struct {
+	SubscriptionToken    string
+	ActivatedDateTime    time.Time
+	ExpiryDateTime       time.Time
+	ExpiredDateTime      time.Time
+	CancellationDateTime time.Time
+	SubscriptionStatus   string
+}
+
+// Account Subscription, subscription tokens of an application held by the
+// account
+type AccountSubscription struct {
+	SubscriptionTokens   []SubscriptionTokenInfo
+	ApplicationName      string
+	ApplicationVersionId string
+}
+
+// Parameters of the OAuth token exchange, Code is set for the
+// AUTHORIZATION_CODE grant and RefreshToken for REFRESH_TOKEN
+type VendorTokenRequest struct {
+	ClientId     string `json:"client_id"`
+	GrantType    string `json:"grant_type"`
+	Code         string `json:"code,omitempty"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// Vendor Access Token Info, the result of the OAuth token exchange
+type VendorAccessTokenInfo struct {
+	AccessToken             string                  `json:"access_token"`
+	TokenType               string                  `json:"token_type"`
+	ExpiresIn               int64                   `json:"expires_in"`
+	RefreshToken            string                  `json:"refresh_token"`
+	ApplicationSubscription ApplicationSubscription `json:"application_subscription"`
+}
+
+// Returns a new subscription token of the vendor application, length is in
+// days and clientReference is optional
+func (s *Session) GetApplicationSubscriptionToken(length int,
+	clientReference string) (string, error) {
+	return s.GetApplicationSubscriptionTokenContext(context.Background(),
+		length, clientReference)
+}
+
+// Like GetApplicationSubscriptionToken, ctx controls the request
+func (s *Session) GetApplicationSubscriptionTokenContext(ctx context.Context,
+	length int, clientReference string) (string, error) {
+	params := struct {
+		SubscriptionLength int    `json:"subscriptionLength,omitempty"`
+		ClientReference    string `json:"clientReference,omitempty"`
+	}{length, clientReference}
+
+	var token string
+	err := accountRequest(ctx, "getApplicationSubscriptionToken", s, params,
+		&token)
+	return token, err
+}
+
+// Activates the subscription token for the session account
+func (s *Session) ActivateApplicationSubscription(token string) error {
+	return s.ActivateApplicationSubscriptionContext(context.Background(), token)
+}
+
+// Like ActivateApplicationSubscription, ctx controls the request
+func (s *Session) ActivateApplicationSubscriptionContext(ctx context.Context,
+	token string) error {
+	return subscriptionRequest(ctx, "activateApplicationSubscription", s,
+		token)
+}
+
+// Cancels the subscription token of the vendor application
+func (s *Session) CancelSubscriptionToken(token string) error {
+	return s.CancelSubscriptionTokenContext(context.Background(), token)
+}
+
+// Like CancelSubscriptionToken, ctx controls the request
+func (s *Session) CancelSubscriptionTokenContext(ctx context.Context,
+	token string) error {
+	return subscriptionRequest(ctx, "cancelSubscriptionToken", s, token)
+}
+
+// Returns subscription tokens of the vendor application with status, every
+// token if status is empty
+func (s *Session) ListApplicationSubscriptionTokens(status string) (
+	[]ApplicationSubscription, error) {
+	return s.ListApplicationSubscriptionTokensContext(context.Background(),
+		status)
+}
+
+// Like ListApplicationSubscriptionTokens, ctx controls the request
+func (s *Session) ListApplicationSubscriptionTokensContext(
+	ctx context.Context, status string) ([]ApplicationSubscription, error) {
+	params := struct {
+		SubscriptionStatus string `json:"subscriptionStatus,omitempty"`
+	}{status}
+
+	var subscriptions []ApplicationSubscription
+	err := accountRequest(ctx, "listApplicationSubscriptionTokens", s, params,
+		&subscriptions)
+	return subscriptions, err
+}
+
+// Returns subscription tokens held by the session account
+func (s *Session) ListAccountSubscriptionTokens() ([]AccountSubscription,
+	error) {
+	return s.ListAccountSubscriptionTokensContext(context.Background())
+}
+
+// Like ListAccountSubscriptionTokens, ctx controls the request
+func (s *Session) ListAccountSubscriptionTokensContext(ctx context.Context) (
+	[]AccountSubscription, error) {
+	var subscriptions []AccountSubscription
+	err := accountRequest(ctx, "listAccountSubscriptionTokens", s, struct{}{},
+		&subscriptions)
+	return subscriptions, err
+}
+
+// Returns subscriptions of the vendor client to the vendor application,
+// applicationKey is optional
+func (s *Session) GetApplicationSubscriptionHistory(vendorClientId,
+	applicationKey string) ([]ApplicationSubscription, error) {
+	return s.GetApplicationSubscriptionHistoryContext(context.Background(),
+		vendorClientId, applicationKey)
+}
+
+// Like GetApplicationSubscriptionHistory, ctx controls the request
+func (s *Session) GetApplicationSubscriptionHistoryContext(
+	ctx context.Context, vendorClientId, applicationKey string) (
+	[]ApplicationSubscription, error) {
+	if vendorClientId == "" {
+		return nil, errRequired("getApplicationSubscriptionHistory",
+			"vendor client id")
+	}
+	params := struct {
+		VendorClientId string `json:"vendorClientId"`
+		ApplicationKey string `json:"applicationKey,omitempty"`
+	}{vendorClientId, applicationKey}
+
+	var subscriptions []ApplicationSubscription
+	err := accountRequest(ctx, "getApplicationSubscriptionHistory", s, params,
+		&subscriptions)
+	return subscriptions, err
+}
+
+// Returns the vendor client id of the session account
+func (s *Session) GetVendorClientId() (string, error) {
+	return s.GetVendorClientIdContext(context.Background())
+}
+
+// Like GetVendorClientId, ctx controls the request
+func (s *Session) GetVendorClientIdContext(ctx context.Context) (string,
+	error) {
+	var id string
+	err := accountRequest(ctx, "getVendorClientId", s, struct{}{}, &id)
+	return id, err
+}
+
+// Returns an authorisation code granting the vendor web application access
+// to the session account, exchanged with Token by the vendor
+func (s *Session) GetAuthorisationCode(vendorId, redirectUrl string) (
+	string, error) {
+	return s.GetAuthorisationCodeContext(context.Background(), vendorId,
+		redirectUrl)
+}
+
+// Like GetAuthorisationCode, ctx controls the request
+func (s *Session) GetAuthorisationCodeContext(ctx context.Context, vendorId,
+	redirectUrl string) (string, error) {
+	if vendorId == "" {
+		return "", errRequired("getAuthorisationCode", "vendor id")
+	}
+	params := struct {
+		VendorId    string `json:"vendorId"`
+		RedirectUrl string `json:"redirectUrl,omitempty"`
+	}{vendorId, redirectUrl}
+
+	var code string
+	err := accountRequest(ctx, "getAuthorisationCode", s, params, &code)
+	return code, err
+}
+
+// Exchanges an authorisation code or refresh token for an access token of
+// the customer account, called with the vendor session
+func (s *Session) Token(r *VendorTokenRequest) (*VendorAccessTokenInfo,
+	error) {
+	return s.TokenContext(context.Background(), r)
+}
+
+// Like Token, ctx controls the request
+func (s *Session) TokenContext(ctx context.Context, r *VendorTokenRequest) (
+	*VendorAccessTokenInfo, error) {
+	if r == nil {
+		return nil, errNilRequest("token")
+	}
+	switch r.GrantType {
+	case GrantTypeAuthorizationCode:
+		if r.Code == "" {
+			return nil, errRequired("token", "code")
+		}
+	case GrantTypeRefreshToken:
+		if r.RefreshToken == "" {
+			return nil, errRequired("token", "refresh token")
+		}
+	default:
+		return nil, fmt.Errorf("token: unknown grant type %q", r.GrantType)
+	}
+
+	var info VendorAccessTokenInfo
+	if err := accountRequest(ctx, "token", s, r, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// sends a subscription token to method, the response status must be
+// SUCCESS
+func subscriptionRequest(ctx context.Context, method string, s *Session,
+	token string) error {
+	if token == "" {
+		return errRequired(method, "subscription token")
+	}
+	params := struct {
+		SubscriptionToken string `json:"subscriptionToken"`
+	}{token}
+
+	var status string
+	if err := accountRequest(ctx, method, s, params, &status); err != nil {
+		return err
+	}
+	if status != "SUCCESS" {
+		return fmt.Errorf("%s: %s", method, status)
+	}
+	return nil
+}